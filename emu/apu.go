@@ -0,0 +1,357 @@
+package emu
+
+import (
+	"math"
+
+	"github.com/is386/NESify/emu/bits"
+)
+
+const (
+	SAMPLE_RATE = 44100
+	SQ1_VOL     = 0x4000
+	SQ1_SWEEP   = 0x4001
+	SQ1_LO      = 0x4002
+	SQ1_HI      = 0x4003
+	SQ2_VOL     = 0x4004
+	SQ2_SWEEP   = 0x4005
+	SQ2_LO      = 0x4006
+	SQ2_HI      = 0x4007
+	TRI_LINEAR  = 0x4008
+	TRI_LO      = 0x400A
+	TRI_HI      = 0x400B
+	NOISE_VOL   = 0x400C
+	NOISE_LO    = 0x400E
+	NOISE_HI    = 0x400F
+	DMC_FREQ    = 0x4010
+	DMC_RAW     = 0x4011
+	DMC_START   = 0x4012
+	DMC_LEN     = 0x4013
+	SND_CHN     = 0x4015
+	FRAME_CTR   = 0x4017
+)
+
+var (
+	LENGTH_TABLE = [32]uint8{
+		10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+		12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+	}
+	PULSE_TABLE [31]float32
+	TND_TABLE   [203]float32
+)
+
+func init() {
+	for i := 1; i < len(PULSE_TABLE); i++ {
+		PULSE_TABLE[i] = float32(95.52 / (8128.0/float64(i) + 100))
+	}
+	for i := 1; i < len(TND_TABLE); i++ {
+		TND_TABLE[i] = float32(163.67 / (24329.0/float64(i) + 100))
+	}
+}
+
+type APU struct {
+	cpu                   *CPU
+	pulse1, pulse2        *Pulse
+	triangle              *Triangle
+	noise                 *Noise
+	dmc                   *DMC
+	frameCyc, sampleClock int
+	sampleRate            int
+	fiveStep, irqInhibit  bool
+	filters               [3]*Filter
+	samples               []float32
+	oddCycle              bool
+}
+
+func NewAPU() *APU {
+	a := &APU{
+		sampleRate: SAMPLE_RATE,
+		pulse1:     NewPulse(1),
+		pulse2:     NewPulse(2),
+		triangle:   NewTriangle(),
+		noise:      NewNoise(),
+		filters: [3]*Filter{
+			NewHighPassFilter(SAMPLE_RATE, 90),
+			NewHighPassFilter(SAMPLE_RATE, 440),
+			NewLowPassFilter(SAMPLE_RATE, 14000),
+		},
+	}
+	a.dmc = NewDMC(a)
+	return a
+}
+
+// The pulse timers count APU cycles, every other CPU cycle, while the other
+// channels' periods are already in CPU cycles.
+func (a *APU) update() {
+	a.triangle.clockTimer()
+	a.noise.clockTimer()
+	a.dmc.clockTimer()
+	if a.oddCycle {
+		a.pulse1.clockTimer()
+		a.pulse2.clockTimer()
+	}
+	a.oddCycle = !a.oddCycle
+	a.clockFrameCounter()
+
+	a.sampleClock += a.sampleRate
+	if a.sampleClock >= CLOCK_SPEED {
+		a.sampleClock -= CLOCK_SPEED
+		a.samples = append(a.samples, a.filter(a.output()))
+	}
+}
+
+// The frame counter is clocked in CPU cycles. Quarter frames drive envelopes
+// and the triangle's linear counter, half frames drive length counters and
+// sweeps.
+func (a *APU) clockFrameCounter() {
+	a.frameCyc++
+	switch a.frameCyc {
+	case 7457, 22371:
+		a.clockQuarterFrame()
+	case 14913:
+		a.clockQuarterFrame()
+		a.clockHalfFrame()
+	case 29829:
+		if !a.fiveStep {
+			a.clockQuarterFrame()
+			a.clockHalfFrame()
+			if !a.irqInhibit {
+				a.cpu.setIrq(IrqFrameCounter)
+			}
+			a.frameCyc = 0
+		}
+	case 37281:
+		a.clockQuarterFrame()
+		a.clockHalfFrame()
+		a.frameCyc = 0
+	}
+}
+
+func (a *APU) clockQuarterFrame() {
+	a.pulse1.envelope.clock()
+	a.pulse2.envelope.clock()
+	a.triangle.clockLinear()
+	a.noise.envelope.clock()
+}
+
+func (a *APU) clockHalfFrame() {
+	a.pulse1.clockLength()
+	a.pulse1.clockSweep()
+	a.pulse2.clockLength()
+	a.pulse2.clockSweep()
+	a.triangle.clockLength()
+	a.noise.clockLength()
+}
+
+func (a *APU) output() float32 {
+	pulse := a.pulse1.output() + a.pulse2.output()
+	tnd := 3*uint16(a.triangle.output()) + 2*uint16(a.noise.output()) + uint16(a.dmc.output())
+	return PULSE_TABLE[pulse] + TND_TABLE[tnd]
+}
+
+func (a *APU) filter(sample float32) float32 {
+	for _, f := range a.filters {
+		sample = f.step(sample)
+	}
+	return sample
+}
+
+func (a *APU) setSampleRate(rate int) {
+	a.sampleRate = rate
+}
+
+func (a *APU) takeSamples() []float32 {
+	samples := a.samples
+	a.samples = nil
+	return samples
+}
+
+func (a *APU) readRegister(addr uint16) uint8 {
+	if addr != SND_CHN {
+		return 0
+	}
+
+	status := uint8(0)
+	if a.pulse1.length > 0 {
+		status = bits.Set(status, 0)
+	}
+	if a.pulse2.length > 0 {
+		status = bits.Set(status, 1)
+	}
+	if a.triangle.length > 0 {
+		status = bits.Set(status, 2)
+	}
+	if a.noise.length > 0 {
+		status = bits.Set(status, 3)
+	}
+	if a.dmc.bytesRemaining > 0 {
+		status = bits.Set(status, 4)
+	}
+	if a.cpu.irq&IrqFrameCounter != 0 {
+		status = bits.Set(status, 6)
+	}
+	if a.cpu.irq&IrqDmc != 0 {
+		status = bits.Set(status, 7)
+	}
+	a.cpu.clearIrq(IrqFrameCounter)
+	return status
+}
+
+// reset silences every channel and restarts the frame counter in the mode it
+// was in.
+func (a *APU) reset() {
+	var frameCtr uint8
+	if a.fiveStep {
+		frameCtr = bits.Set(frameCtr, 7)
+	}
+	if a.irqInhibit {
+		frameCtr = bits.Set(frameCtr, 6)
+	}
+	a.writeRegister(SND_CHN, 0)
+	a.writeRegister(FRAME_CTR, frameCtr)
+	a.cpu.clearIrq(IrqFrameCounter)
+}
+
+func (a *APU) writeRegister(addr uint16, val uint8) {
+	switch {
+
+	case addr <= SQ1_HI:
+		a.pulse1.writeRegister(addr-SQ1_VOL, val)
+
+	case addr <= SQ2_HI:
+		a.pulse2.writeRegister(addr-SQ2_VOL, val)
+
+	case addr <= TRI_HI:
+		a.triangle.writeRegister(addr-TRI_LINEAR, val)
+
+	case addr <= NOISE_HI:
+		a.noise.writeRegister(addr-NOISE_VOL, val)
+
+	case addr <= DMC_LEN:
+		a.dmc.writeRegister(addr-DMC_FREQ, val)
+
+	case addr == SND_CHN:
+		a.pulse1.setEnabled(bits.Test(val, 0))
+		a.pulse2.setEnabled(bits.Test(val, 1))
+		a.triangle.setEnabled(bits.Test(val, 2))
+		a.noise.setEnabled(bits.Test(val, 3))
+		a.dmc.setEnabled(bits.Test(val, 4))
+		a.cpu.clearIrq(IrqDmc)
+
+	case addr == FRAME_CTR:
+		a.fiveStep = bits.Test(val, 7)
+		a.irqInhibit = bits.Test(val, 6)
+		if a.irqInhibit {
+			a.cpu.clearIrq(IrqFrameCounter)
+		}
+		a.frameCyc = 0
+		if a.fiveStep {
+			a.clockQuarterFrame()
+			a.clockHalfFrame()
+		}
+	}
+}
+
+type Envelope struct {
+	start, loop, constant bool
+	volume, divider       uint8
+	decay                 uint8
+}
+
+func (e *Envelope) write(val uint8) {
+	e.loop = bits.Test(val, 5)
+	e.constant = bits.Test(val, 4)
+	e.volume = val & 0x0F
+}
+
+func (e *Envelope) clock() {
+	if e.start {
+		e.start = false
+		e.decay = 15
+		e.divider = e.volume
+		return
+	}
+	if e.divider > 0 {
+		e.divider--
+		return
+	}
+	e.divider = e.volume
+	if e.decay > 0 {
+		e.decay--
+	} else if e.loop {
+		e.decay = 15
+	}
+}
+
+func (e *Envelope) output() uint8 {
+	if e.constant {
+		return e.volume
+	}
+	return e.decay
+}
+
+type Filter struct {
+	b0, b1, a1   float32
+	prevX, prevY float32
+}
+
+func NewLowPassFilter(sampleRate, cutoff float64) *Filter {
+	c := sampleRate / math.Pi / cutoff
+	a0 := 1 / (1 + c)
+	return &Filter{b0: float32(a0), b1: float32(a0), a1: float32((1 - c) * a0)}
+}
+
+func NewHighPassFilter(sampleRate, cutoff float64) *Filter {
+	c := sampleRate / math.Pi / cutoff
+	a0 := 1 / (1 + c)
+	return &Filter{b0: float32(c * a0), b1: float32(-c * a0), a1: float32((1 - c) * a0)}
+}
+
+func (f *Filter) step(x float32) float32 {
+	y := f.b0*x + f.b1*f.prevX - f.a1*f.prevY
+	f.prevX, f.prevY = x, y
+	return y
+}
+
+func (a *APU) save(w *stateWriter) {
+	a.pulse1.save(w)
+	a.pulse2.save(w)
+	a.triangle.save(w)
+	a.noise.save(w)
+	a.dmc.save(w)
+	w.i64(a.frameCyc)
+	w.i64(a.sampleClock)
+	w.bool(a.fiveStep)
+	w.bool(a.irqInhibit)
+	w.bool(a.oddCycle)
+}
+
+func (a *APU) load(r *stateReader) {
+	a.pulse1.load(r)
+	a.pulse2.load(r)
+	a.triangle.load(r)
+	a.noise.load(r)
+	a.dmc.load(r)
+	a.frameCyc = r.i64()
+	a.sampleClock = r.i64()
+	a.fiveStep = r.bool()
+	a.irqInhibit = r.bool()
+	a.oddCycle = r.bool()
+}
+
+func (e *Envelope) save(w *stateWriter) {
+	w.bool(e.start)
+	w.bool(e.loop)
+	w.bool(e.constant)
+	w.u8(e.volume)
+	w.u8(e.divider)
+	w.u8(e.decay)
+}
+
+func (e *Envelope) load(r *stateReader) {
+	e.start = r.bool()
+	e.loop = r.bool()
+	e.constant = r.bool()
+	e.volume = r.u8()
+	e.divider = r.u8()
+	e.decay = r.u8()
+}
@@ -0,0 +1,76 @@
+package emu
+
+import "github.com/is386/NESify/emu/bits"
+
+type AxROM struct {
+	prg, chr  []uint8
+	chrRam    bool
+	conflicts bool
+	prgBank   int
+	mirroring Mirroring
+}
+
+func newAxROM(c *Cart) Mapper {
+	return &AxROM{}
+}
+
+func (a *AxROM) loadRom(h *Header, prg, chr []uint8) {
+	a.prg = prg
+	a.chr, a.chrRam = newChr(h, chr)
+	a.conflicts = hasBusConflicts(h, false)
+	a.mirroring = SingleScreenA
+}
+
+func (a *AxROM) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		return a.chr[int(addr)%len(a.chr)]
+
+	case addr >= 0x8000:
+		return a.prg[(a.prgBank*0x8000+int(addr-0x8000))%len(a.prg)]
+
+	default:
+		return 0
+	}
+}
+
+func (a *AxROM) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000 && a.chrRam:
+		a.chr[int(addr)%len(a.chr)] = val
+
+	case addr >= 0x8000:
+		if a.conflicts {
+			val &= a.read(addr)
+		}
+		a.prgBank = int(val & 7)
+		if bits.Test(val, 4) {
+			a.mirroring = SingleScreenB
+		} else {
+			a.mirroring = SingleScreenA
+		}
+	}
+}
+
+func (a *AxROM) getMirroring() Mirroring {
+	return a.mirroring
+}
+
+func (a *AxROM) save(w *stateWriter) {
+	if a.chrRam {
+		w.bytes(a.chr)
+	}
+	w.i64(a.prgBank)
+	w.i64(int(a.mirroring))
+}
+
+func (a *AxROM) load(r *stateReader) {
+	if a.chrRam {
+		r.bytes(a.chr)
+	}
+	a.prgBank = r.index(8)
+	a.mirroring = Mirroring(r.i64())
+	r.check(a.mirroring == SingleScreenA || a.mirroring == SingleScreenB, "mirroring")
+}
@@ -0,0 +1,64 @@
+package emu
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Battery struct {
+	path  string
+	ram   []uint8
+	saved []uint8
+}
+
+func SavePath(romFileName string) string {
+	return strings.TrimSuffix(romFileName, filepath.Ext(romFileName)) + ".sav"
+}
+
+func NewBattery(path string, ram []uint8) (*Battery, error) {
+	b := &Battery{path: path, ram: ram}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	copy(b.ram, data)
+	b.saved = append([]uint8{}, b.ram...)
+	return b, nil
+}
+
+func (b *Battery) Flush() error {
+	if bytes.Equal(b.ram, b.saved) {
+		return nil
+	}
+	if err := writeFileAtomic(b.path, b.ram); err != nil {
+		return err
+	}
+	copy(b.saved, b.ram)
+	return nil
+}
+
+// The save is written to a temporary file in the same directory and renamed
+// over the old one, so a crash mid-write leaves the previous save intact.
+func writeFileAtomic(path string, data []uint8) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
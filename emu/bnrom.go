@@ -0,0 +1,104 @@
+package emu
+
+// Mapper 34 covers two unrelated boards: BNROM, which only switches 32KB of
+// PRG-ROM, and NINA-001, which also switches two 4KB CHR banks and keeps its
+// registers at the top of PRG-RAM.
+type BNROM struct {
+	prg, chr, prgRam []uint8
+	chrRam           bool
+	conflicts, nina  bool
+	prgBank          int
+	chrBanks         [2]int
+	mirroring        Mirroring
+}
+
+func newBNROM(c *Cart) Mapper {
+	return &BNROM{}
+}
+
+func (b *BNROM) loadRom(h *Header, prg, chr []uint8) {
+	b.prg = prg
+	b.chr, b.chrRam = newChr(h, chr)
+	b.nina = h.submapper == 1 || (h.submapper == 0 && len(chr) > 0x2000)
+	b.conflicts = !b.nina
+	b.chrBanks = [2]int{0, 1}
+	if b.nina {
+		b.prgRam = newPrgRam(h)
+	}
+	b.mirroring = h.mirroring
+}
+
+func (b *BNROM) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		return b.chr[b.chrOffset(addr)]
+
+	case addr >= 0x6000 && addr < 0x8000 && len(b.prgRam) > 0:
+		return b.prgRam[int(addr-0x6000)%len(b.prgRam)]
+
+	case addr >= 0x8000:
+		return b.prg[(b.prgBank*0x8000+int(addr-0x8000))%len(b.prg)]
+
+	default:
+		return 0
+	}
+}
+
+func (b *BNROM) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000 && b.chrRam:
+		b.chr[b.chrOffset(addr)] = val
+
+	case addr >= 0x6000 && addr < 0x8000 && len(b.prgRam) > 0:
+		b.prgRam[int(addr-0x6000)%len(b.prgRam)] = val
+		switch addr {
+		case 0x7FFD:
+			b.prgBank = int(val & 1)
+		case 0x7FFE:
+			b.chrBanks[0] = int(val & 0x0F)
+		case 0x7FFF:
+			b.chrBanks[1] = int(val & 0x0F)
+		}
+
+	case addr >= 0x8000 && !b.nina:
+		if b.conflicts {
+			val &= b.read(addr)
+		}
+		b.prgBank = int(val)
+	}
+}
+
+func (b *BNROM) getMirroring() Mirroring {
+	return b.mirroring
+}
+
+func (b *BNROM) getPrgRam() []uint8 {
+	return b.prgRam
+}
+
+func (b *BNROM) chrOffset(addr uint16) int {
+	bank := b.chrBanks[addr/0x1000]
+	return (bank*0x1000 + int(addr%0x1000)) % len(b.chr)
+}
+
+func (b *BNROM) save(w *stateWriter) {
+	if b.chrRam {
+		w.bytes(b.chr)
+	}
+	w.bytes(b.prgRam)
+	w.i64(b.prgBank)
+	w.i64(b.chrBanks[0])
+	w.i64(b.chrBanks[1])
+}
+
+func (b *BNROM) load(r *stateReader) {
+	if b.chrRam {
+		r.bytes(b.chr)
+	}
+	r.bytes(b.prgRam)
+	b.prgBank = r.index(0x100)
+	b.chrBanks[0] = r.index(0x10)
+	b.chrBanks[1] = r.index(0x10)
+}
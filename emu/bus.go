@@ -4,11 +4,13 @@ type CpuBus struct {
 	ram         [0x800]uint8
 	cart        *Cart
 	ppu         *PPU
+	apu         *APU
 	controllers *Controllers
+	dma         *DMA
 }
 
-func NewCpuBus(c *Cart, ppu *PPU, controllers *Controllers) *CpuBus {
-	b := &CpuBus{cart: c, ppu: ppu, controllers: controllers}
+func NewCpuBus(c *Cart, ppu *PPU, apu *APU, controllers *Controllers) *CpuBus {
+	b := &CpuBus{cart: c, ppu: ppu, apu: apu, controllers: controllers}
 	return b
 }
 
@@ -21,17 +23,36 @@ func (bus *CpuBus) read(addr uint16) uint8 {
 	case addr < 0x4000 || addr == 0x4014:
 		return bus.ppu.readRegister(addr)
 
+	case addr == 0x4015:
+		return bus.apu.readRegister(addr)
+
 	case addr == 0x4016:
-		return bus.controllers.readController1()
+		return bus.controllers.read(0)
+
+	case addr == 0x4017:
+		return bus.controllers.read(1)
 
 	case addr < 0x4020:
 		return 0
 
-	case addr < 0xFFFF:
+	default:
 		return bus.cart.read(addr)
+	}
+}
 
-	default:
+// Reading PPU, APU and controller registers has side effects, so peeking at
+// them only returns 0.
+func (bus *CpuBus) peek(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		return bus.ram[addr%0x800]
+
+	case addr < 0x4020:
 		return 0
+
+	default:
+		return bus.cart.read(addr)
 	}
 }
 
@@ -41,24 +62,32 @@ func (bus *CpuBus) write(addr uint16, val uint8) {
 	case addr < 0x2000:
 		bus.ram[addr%0x800] = val
 
-	case addr < 0x4000 || addr == 0x4014:
+	case addr < 0x4000:
 		bus.ppu.writeRegister(addr, val)
 
+	case addr == OAMDMA:
+		bus.dma.startOam(val)
+
 	case addr == 0x4016:
-		bus.controllers.enablePolling(val)
+		bus.controllers.write(val)
+
+	case addr < 0x4018:
+		bus.apu.writeRegister(addr, val)
 
 	case addr < 0x4020:
 		return
 
-	case addr < 0xFFFF:
+	default:
 		bus.cart.write(addr, val)
 	}
 }
 
 type PpuBus struct {
-	vram [0x8000]uint8
-	oam  [0x0100]uint8
-	cart *Cart
+	ciram   [0x800]uint8
+	palette [0x20]uint8
+	oam     [0x0100]uint8
+	cart    *Cart
+	clock   uint64
 }
 
 func NewPpuBus(c *Cart) *PpuBus {
@@ -67,33 +96,43 @@ func NewPpuBus(c *Cart) *PpuBus {
 }
 
 func (bus *PpuBus) read(addr uint16) uint8 {
+	addr %= 0x4000
+	if addr < 0x2000 {
+		bus.cart.watchA12(addr, bus.clock)
+	}
+	return bus.peek(addr)
+}
+
+func (bus *PpuBus) peek(addr uint16) uint8 {
+	addr %= 0x4000
 	switch {
 
 	case addr < 0x2000:
 		return bus.cart.read(addr)
 
-	case addr >= 0x3F00 && addr <= 0x3F1F:
-		return bus.vram[bus.mirrorPalette(addr)]
-
-	case addr < 0x4000:
-		return bus.vram[addr]
+	case addr < 0x3F00:
+		ram, i := bus.mirrorNameTable(addr)
+		return ram[i]
 
 	default:
-		return 0
+		return bus.palette[bus.mirrorPalette(addr)]
 	}
 }
 
 func (bus *PpuBus) write(addr uint16, val uint8) {
+	addr %= 0x4000
 	switch {
 
 	case addr < 0x2000:
+		bus.cart.watchA12(addr, bus.clock)
 		bus.cart.write(addr, val)
 
-	case addr >= 0x3F00 && addr <= 0x3F1F:
-		bus.vram[bus.mirrorPalette(addr)] = val
+	case addr < 0x3F00:
+		ram, i := bus.mirrorNameTable(addr)
+		ram[i] = val
 
-	case addr < 0x4000:
-		bus.vram[addr] = val
+	default:
+		bus.palette[bus.mirrorPalette(addr)] = val
 	}
 }
 
@@ -105,17 +144,57 @@ func (bus *PpuBus) writeOam(addr uint8, val uint8) {
 	bus.oam[addr] = val
 }
 
-func (bus *PpuBus) mirrorPalette(addr uint16) uint16 {
-	switch addr {
-	case 0x3F10:
-		return 0x3F00
-	case 0x3F14:
-		return 0x3F04
-	case 0x3F18:
-		return 0x3F08
-	case 0x3F1C:
-		return 0x3F0C
+// The four logical nametables at $2000-$2FFF (mirrored up to $3EFF) share the
+// console's 2KB of CIRAM according to the cart's mirroring mode. Four-screen
+// carts supply their own 2KB for the second pair.
+func (bus *PpuBus) mirrorNameTable(addr uint16) ([]uint8, uint16) {
+	addr = (addr - 0x2000) % 0x1000
+	table := addr / 0x400
+	offset := addr % 0x400
+
+	switch bus.cart.getMirroring() {
+	case Horizontal:
+		return bus.ciram[:], (table/2)*0x400 + offset
+	case Vertical:
+		return bus.ciram[:], (table%2)*0x400 + offset
+	case SingleScreenA:
+		return bus.ciram[:], offset
+	case SingleScreenB:
+		return bus.ciram[:], 0x400 + offset
 	default:
-		return addr
+		if table < 2 {
+			return bus.ciram[:], table*0x400 + offset
+		}
+		return bus.cart.vram, (table-2)*0x400 + offset
+	}
+}
+
+func (bus *PpuBus) mirrorPalette(addr uint16) uint16 {
+	addr %= 0x20
+	if addr >= 0x10 && addr%4 == 0 {
+		addr -= 0x10
 	}
+	return addr
+}
+
+func (bus *CpuBus) save(w *stateWriter) {
+	w.bytes(bus.ram[:])
+}
+
+func (bus *CpuBus) load(r *stateReader) {
+	r.bytes(bus.ram[:])
+}
+
+func (bus *PpuBus) save(w *stateWriter) {
+	w.bytes(bus.ciram[:])
+	w.bytes(bus.palette[:])
+	w.bytes(bus.oam[:])
+	w.u64(bus.clock)
+}
+
+func (bus *PpuBus) load(r *stateReader) {
+	r.bytes(bus.ciram[:])
+	r.bytes(bus.palette[:])
+	r.bytes(bus.oam[:])
+	bus.clock = r.u64()
 }
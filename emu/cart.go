@@ -1,13 +1,61 @@
 package emu
 
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+var (
+	mappers = map[uint16]func(*Cart) Mapper{
+		0:  newNROM,
+		1:  newMMC1,
+		2:  newUxROM,
+		3:  newCNROM,
+		4:  newMMC3,
+		7:  newAxROM,
+		11: newColorDreams,
+		34: newBNROM,
+		66: newGxROM,
+	}
+)
+
 type Cart struct {
+	header *Header
 	mapper Mapper
+	a12    a12Watcher
+	cpu    *CPU
+	vram   []uint8
+	hash   [sha1.Size]uint8
 }
 
-func NewCart(rom []uint8) *Cart {
-	c := &Cart{mapper: newNROM()}
-	c.mapper.loadRom(rom)
-	return c
+func NewCart(rom []uint8) (*Cart, error) {
+	h, err := parseHeader(rom)
+	if err != nil {
+		return nil, err
+	}
+
+	newMapper, ok := mappers[h.mapper]
+	if !ok {
+		return nil, fmt.Errorf("unsupported mapper %d", h.mapper)
+	}
+
+	prgStart := h.prgStart()
+	chrStart := prgStart + h.prgRomSize
+	prg := rom[prgStart:chrStart]
+	chr := rom[chrStart : chrStart+h.chrRomSize]
+
+	c := &Cart{header: h, hash: sha1.Sum(rom)}
+	c.mapper = newMapper(c)
+	if b, ok := c.mapper.(prgBanked); ok && len(prg)%b.prgBankSize() != 0 {
+		return nil, fmt.Errorf("%w: %d bytes of PRG-ROM is not a whole number of %d byte banks",
+			ErrNotNesFile, len(prg), b.prgBankSize())
+	}
+	c.mapper.loadRom(h, prg, chr)
+	c.a12, _ = c.mapper.(a12Watcher)
+	if h.mirroring == FourScreen {
+		c.vram = make([]uint8, 0x800)
+	}
+	return c, nil
 }
 
 func (c *Cart) read(addr uint16) uint8 {
@@ -17,3 +65,71 @@ func (c *Cart) read(addr uint16) uint8 {
 func (c *Cart) write(addr uint16, val uint8) {
 	c.mapper.write(addr, val)
 }
+
+func (c *Cart) getMirroring() Mirroring {
+	return c.mapper.getMirroring()
+}
+
+func (c *Cart) hasBattery() bool {
+	_, ok := c.mapper.(batteryBacked)
+	return c.header.battery && ok && len(c.getPrgRam()) > 0
+}
+
+func (c *Cart) getPrgRam() []uint8 {
+	if m, ok := c.mapper.(batteryBacked); ok {
+		return m.getPrgRam()
+	}
+	return nil
+}
+
+func (c *Cart) watchA12(addr uint16, clock uint64) {
+	if c.a12 != nil {
+		c.a12.watchA12(addr, clock)
+	}
+}
+
+func (c *Cart) IsNes20() bool {
+	return c.header.nes20
+}
+
+func (c *Cart) MapperNumber() uint16 {
+	return c.header.mapper
+}
+
+func (c *Cart) Submapper() uint8 {
+	return c.header.submapper
+}
+
+func (c *Cart) PrgRamSize() int {
+	return c.header.prgRamSize
+}
+
+func (c *Cart) PrgNvramSize() int {
+	return c.header.prgNvramSize
+}
+
+func (c *Cart) ChrRamSize() int {
+	return c.header.chrRamSize
+}
+
+func (c *Cart) ChrNvramSize() int {
+	return c.header.chrNvramSize
+}
+
+func (c *Cart) ConsoleType() ConsoleType {
+	return c.header.console
+}
+
+func (c *Cart) Timing() Timing {
+	return c.header.timing
+}
+
+func (c *Cart) save(w *stateWriter) {
+	w.bytes(c.vram)
+	c.mapper.save(w)
+}
+
+func (c *Cart) load(r *stateReader) {
+	r.bytes(c.vram)
+	c.mapper.load(r)
+}
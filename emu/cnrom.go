@@ -0,0 +1,66 @@
+package emu
+
+type CNROM struct {
+	prg, chr  []uint8
+	chrRam    bool
+	conflicts bool
+	chrBank   int
+	mirroring Mirroring
+}
+
+func newCNROM(c *Cart) Mapper {
+	return &CNROM{}
+}
+
+func (n *CNROM) loadRom(h *Header, prg, chr []uint8) {
+	n.prg = prg
+	n.chr, n.chrRam = newChr(h, chr)
+	n.conflicts = hasBusConflicts(h, true)
+	n.mirroring = h.mirroring
+}
+
+func (n *CNROM) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		return n.chr[(n.chrBank*0x2000+int(addr))%len(n.chr)]
+
+	case addr >= 0x8000:
+		return n.prg[int(addr-0x8000)%len(n.prg)]
+
+	default:
+		return 0
+	}
+}
+
+func (n *CNROM) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000 && n.chrRam:
+		n.chr[(n.chrBank*0x2000+int(addr))%len(n.chr)] = val
+
+	case addr >= 0x8000:
+		if n.conflicts {
+			val &= n.read(addr)
+		}
+		n.chrBank = int(val)
+	}
+}
+
+func (n *CNROM) getMirroring() Mirroring {
+	return n.mirroring
+}
+
+func (n *CNROM) save(w *stateWriter) {
+	if n.chrRam {
+		w.bytes(n.chr)
+	}
+	w.i64(n.chrBank)
+}
+
+func (n *CNROM) load(r *stateReader) {
+	if n.chrRam {
+		r.bytes(n.chr)
+	}
+	n.chrBank = r.index(0x100)
+}
@@ -0,0 +1,69 @@
+package emu
+
+type ColorDreams struct {
+	prg, chr         []uint8
+	chrRam           bool
+	conflicts        bool
+	prgBank, chrBank int
+	mirroring        Mirroring
+}
+
+func newColorDreams(c *Cart) Mapper {
+	return &ColorDreams{}
+}
+
+func (d *ColorDreams) loadRom(h *Header, prg, chr []uint8) {
+	d.prg = prg
+	d.chr, d.chrRam = newChr(h, chr)
+	d.conflicts = hasBusConflicts(h, true)
+	d.mirroring = h.mirroring
+}
+
+func (d *ColorDreams) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		return d.chr[(d.chrBank*0x2000+int(addr))%len(d.chr)]
+
+	case addr >= 0x8000:
+		return d.prg[(d.prgBank*0x8000+int(addr-0x8000))%len(d.prg)]
+
+	default:
+		return 0
+	}
+}
+
+func (d *ColorDreams) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000 && d.chrRam:
+		d.chr[(d.chrBank*0x2000+int(addr))%len(d.chr)] = val
+
+	case addr >= 0x8000:
+		if d.conflicts {
+			val &= d.read(addr)
+		}
+		d.prgBank = int(val & 3)
+		d.chrBank = int(val >> 4)
+	}
+}
+
+func (d *ColorDreams) getMirroring() Mirroring {
+	return d.mirroring
+}
+
+func (d *ColorDreams) save(w *stateWriter) {
+	if d.chrRam {
+		w.bytes(d.chr)
+	}
+	w.i64(d.prgBank)
+	w.i64(d.chrBank)
+}
+
+func (d *ColorDreams) load(r *stateReader) {
+	if d.chrRam {
+		r.bytes(d.chr)
+	}
+	d.prgBank = r.index(4)
+	d.chrBank = r.index(0x10)
+}
@@ -1,9 +1,5 @@
 package emu
 
-import (
-	"github.com/veandco/go-sdl2/sdl"
-)
-
 type Button uint8
 
 const (
@@ -17,71 +13,58 @@ const (
 	Right
 )
 
-var (
-	buttonMap = map[sdl.Keycode]Button{
-		sdl.K_RETURN: Start,
-		sdl.K_RSHIFT: Select,
-		sdl.K_w:      Up,
-		sdl.K_s:      Down,
-		sdl.K_a:      Left,
-		sdl.K_d:      Right,
-		sdl.K_j:      A,
-		sdl.K_k:      B,
-	}
-)
-
 type Controllers struct {
-	buttons   [8]Button
-	pollInput int
+	buttons [2][8]bool
+	shift   [2]uint8
+	strobe  bool
 }
 
 func NewControllers() *Controllers {
 	return &Controllers{}
 }
 
-func (c *Controllers) update() bool {
-	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-		switch e := event.(type) {
-		case *sdl.QuitEvent:
-			return false
-		case *sdl.KeyboardEvent:
-			switch e.Type {
-			case sdl.KEYDOWN:
-				c.keyDown(e.Keysym.Sym)
-			case sdl.KEYUP:
-				c.keyUp(e.Keysym.Sym)
-			}
-		}
+func (c *Controllers) setButton(port int, b Button, pressed bool) {
+	if port < 0 || port >= len(c.buttons) || int(b) >= len(c.buttons[port]) {
+		return
 	}
-	return true
+	c.buttons[port][b] = pressed
 }
 
-func (c *Controllers) keyDown(key sdl.Keycode) {
-	if val, ok := buttonMap[key]; ok {
-		c.buttons[val] = 1
+func (c *Controllers) state(port int) uint8 {
+	state := uint8(0)
+	for i, pressed := range c.buttons[port] {
+		if pressed {
+			state |= 1 << i
+		}
 	}
+	return state
 }
 
-func (c *Controllers) keyUp(key sdl.Keycode) {
-	if val, ok := buttonMap[key]; ok {
-		c.buttons[val] = 0
+// While strobe is high the shift registers keep reloading, so reads return A.
+// Once it drops, each read shifts out the next button, then 1s after the 8th.
+func (c *Controllers) read(port int) uint8 {
+	if c.strobe {
+		c.shift[port] = c.state(port)
 	}
+	val := c.shift[port] & 1
+	c.shift[port] = (c.shift[port] >> 1) | 0x80
+	return val | 0x40
 }
 
-func (c *Controllers) readController1() uint8 {
-	if c.pollInput >= 0 {
-		val := uint8(c.buttons[c.pollInput])
-		c.pollInput++
-		if c.pollInput > 7 {
-			c.pollInput = -1
-		}
-		return val | 0x40
+func (c *Controllers) write(val uint8) {
+	c.strobe = (val & 1) == 1
+	if c.strobe {
+		c.shift[0] = c.state(0)
+		c.shift[1] = c.state(1)
 	}
-	return 0x40
 }
 
-func (c *Controllers) enablePolling(val uint8) {
-	if val != 0 {
-		c.pollInput = 0
-	}
+func (c *Controllers) save(w *stateWriter) {
+	w.bytes(c.shift[:])
+	w.bool(c.strobe)
+}
+
+func (c *Controllers) load(r *stateReader) {
+	r.bytes(c.shift[:])
+	c.strobe = r.bool()
 }
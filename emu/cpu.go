@@ -1,12 +1,21 @@
 package emu
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io"
 
 	"github.com/is386/NESify/emu/bits"
 )
 
+const (
+	RESET_CYCLES = 7
+)
+
+var (
+	ErrCpuHalted = errors.New("CPU halted")
+)
+
 type Interrupt int
 
 const (
@@ -14,6 +23,14 @@ const (
 	NoInterrupt
 )
 
+type IrqSource uint8
+
+const (
+	IrqMapper IrqSource = 1 << iota
+	IrqFrameCounter
+	IrqDmc
+)
+
 var instructionNames = [256]string{
 	"BRK", "ORA", "KIL", "SLO", "NOP", "ORA", "ASL", "SLO",
 	"PHP", "ORA", "ASL", "ANC", "NOP", "ORA", "ASL", "SLO",
@@ -43,64 +60,117 @@ var instructionNames = [256]string{
 	"INY", "CMP", "DEX", "AXS", "CPY", "CMP", "DEC", "DCP",
 	"BNE", "CMP", "KIL", "DCP", "NOP", "CMP", "DEC", "DCP",
 	"CLD", "CMP", "NOP", "DCP", "NOP", "CMP", "DEC", "DCP",
-	"CPX", "SBC", "NOP", "ISC", "CPX", "SBC", "INC", "ISC",
-	"INX", "SBC", "NOP", "SBC", "CPX", "SBC", "INC", "ISC",
-	"BEQ", "SBC", "KIL", "ISC", "NOP", "SBC", "INC", "ISC",
-	"SED", "SBC", "NOP", "ISC", "NOP", "SBC", "INC", "ISC",
+	"CPX", "SBC", "NOP", "ISB", "CPX", "SBC", "INC", "ISB",
+	"INX", "SBC", "NOP", "SBC", "CPX", "SBC", "INC", "ISB",
+	"BEQ", "SBC", "KIL", "ISB", "NOP", "SBC", "INC", "ISB",
+	"SED", "SBC", "NOP", "ISB", "NOP", "SBC", "INC", "ISB",
 }
 
 type CPU struct {
-	totalCyc, cyc, stall int
-	a, x, y, s           uint8
-	pc                   uint16
-	p                    *Status
-	instr                Instruction
-	bus                  *CpuBus
-	interrupt            Interrupt
-	debug                bool
-}
-
-func NewCPU(bus *CpuBus, debug bool) *CPU {
-	return &CPU{
+	totalCyc, cyc, stall   int
+	a, x, y, s             uint8
+	pc                     uint16
+	p                      *Status
+	instr                  Instruction
+	bus                    *CpuBus
+	dma                    *DMA
+	interrupt              Interrupt
+	irq                    IrqSource
+	nmiPending, irqPending bool
+	runNmi, runIrq         bool
+	halted                 bool
+	trace                  io.Writer
+}
+
+// NewCPU starts the CPU at the reset vector, which the reset sequence takes
+// RESET_CYCLES to get to. Each instruction is logged to trace if it is set.
+func NewCPU(bus *CpuBus, trace io.Writer) *CPU {
+	c := &CPU{
 		pc:        (uint16(bus.read(0xFFFC+1)) << 8) | uint16(bus.read(0xFFFC)),
 		s:         0xFD,
 		p:         NewStatus(),
 		bus:       bus,
-		debug:     debug,
+		trace:     trace,
 		interrupt: NoInterrupt,
+		stall:     RESET_CYCLES,
 	}
+	c.dma = NewDMA(c)
+	bus.dma = c.dma
+	return c
 }
 
+// reset runs the reset sequence, which is an interrupt with its stack writes
+// turned into reads, so S moves but nothing is pushed.
+func (c *CPU) reset() {
+	c.pc = (uint16(c.bus.read(0xFFFC+1)) << 8) | uint16(c.bus.read(0xFFFC))
+	c.s -= 3
+	c.p.setInterrupt()
+	c.interrupt = NoInterrupt
+	c.nmiPending, c.irqPending, c.runNmi, c.runIrq = false, false, false, false
+	c.halted = false
+	c.stall = RESET_CYCLES
+}
+
+// update runs one instruction, or one cycle of the reset sequence or of being
+// halted, and returns the number of cycles taken, including any DMA. Every
+// cycle is a bus read or write, and the PPU and APU catch up before each one.
 func (c *CPU) update() int {
+	c.cyc = 0
 	if c.stall > 0 {
 		c.stall--
-		return 1
+		c.tick()
+		return c.cyc
+	}
+	// A halted CPU keeps reading $FFFF, which still lets DMA through.
+	if c.halted {
+		c.read(0xFFFF)
+		return c.cyc
 	}
-	c.print()
-	c.cyc = 0
 	c.checkInterrupts()
+	c.print()
 	opcode := c.fetch()
 	c.instr = c.decode(opcode)
 	operand := c.getOperand(c.instr.addrMode)
 	c.instr.function(c, operand)
-	c.cyc += c.instr.cyc
-	c.totalCyc += c.cyc
 	return c.cyc
 }
 
 func (c *CPU) print() {
-	if c.debug {
-		fmt.Printf("%04X %s A:%02X X:%02X Y:%02X P:%02X SP:%02X\n",
-			c.pc, instructionNames[c.read(c.pc)], c.a, c.x, c.y, c.p.getStatus(), c.s)
+	if c.trace != nil {
+		fmt.Fprintln(c.trace, c.traceLine())
+	}
+}
+
+func (c *CPU) tick() {
+	for i := 0; i < 3; i++ {
+		c.bus.ppu.update()
 	}
+	c.bus.apu.update()
+	c.cyc++
+	c.totalCyc++
 }
 
 func (c *CPU) read(addr uint16) uint8 {
-	return c.bus.read(addr)
+	c.dma.run(addr)
+	c.tick()
+	val := c.bus.read(addr)
+	c.poll()
+	return val
 }
 
 func (c *CPU) write(addr uint16, val uint8) {
+	c.tick()
 	c.bus.write(addr, val)
+	c.poll()
+}
+
+// poll samples the interrupt lines at the end of every cycle. Whether an
+// interrupt is taken after an instruction depends on the sample from its
+// second-to-last cycle, which is the one before the latest.
+func (c *CPU) poll() {
+	c.runNmi, c.runIrq = c.nmiPending, c.irqPending
+	c.nmiPending = c.interrupt == Nmi
+	c.irqPending = c.irq != 0 && c.p.getInterrupt() == 0
 }
 
 func (c *CPU) nextByte() uint8 {
@@ -115,87 +185,84 @@ func (c *CPU) nextTwoBytes() uint16 {
 	return (uint16(b) << 8) | uint16(a)
 }
 
+// readTwoBytes reads JMP's indirect address, which never carries into the
+// high byte of the pointer.
 func (c *CPU) readTwoBytes() uint16 {
 	a := c.nextByte()
 	b := c.nextByte()
 	addr := (uint16(b) << 8) | uint16(a)
 	a = c.read(addr)
-	b = c.read(addr + 1)
-	if (addr & 0xFF) == 0xFF {
-		b = c.read(addr & 0xF00)
-	}
+	b = c.read(addr&0xFF00 | (addr+1)&0xFF)
 	addr = (uint16(b) << 8) | uint16(a)
 	return addr
 }
 
-func (c *CPU) readTwoBytesIndexed(addr uint16) uint16 {
-	lo := c.read((addr + 1) & 0xFF)
-	hi := c.read(addr & 0xFF)
-	return uint16(hi) | uint16(lo)<<8
+// readPointer reads a pointer from the zero page, wrapping within it.
+func (c *CPU) readPointer(zp uint8) uint16 {
+	lo := c.read(uint16(zp))
+	hi := c.read(uint16(zp + 1))
+	return uint16(hi)<<8 | uint16(lo)
 }
 
-func (c *CPU) checkPage(a, b uint16) {
-	if (a & 0xFF00) != (b & 0xFF00) {
-		c.cyc += c.instr.pageCyc
+// indexed adds index to base. The CPU adds it to the low byte first and reads
+// from that address while it fixes the high byte. Read instructions, which
+// have a pageCyc, only do so when the page changes.
+func (c *CPU) indexed(base uint16, index uint8) uint16 {
+	addr := base + uint16(index)
+	if (base&0xFF00) != (addr&0xFF00) || c.instr.pageCyc == 0 {
+		c.read(base&0xFF00 | addr&0xFF)
 	}
+	return addr
 }
 
 func (c *CPU) getOperand(addrMode AddrMode) uint16 {
 	switch addrMode {
 
-	case Acc:
+	case Acc, Imp:
+		c.read(c.pc)
 		return 0
 
 	case Abs:
 		return c.nextTwoBytes()
 
 	case Abx:
-		addr := c.nextTwoBytes() + uint16(c.x)
-		c.checkPage(addr-uint16(c.x), addr)
-		return addr
+		return c.indexed(c.nextTwoBytes(), c.x)
 
 	case Aby:
-		addr := c.nextTwoBytes() + uint16(c.y)
-		c.checkPage(addr-uint16(c.y), addr)
-		return addr
+		return c.indexed(c.nextTwoBytes(), c.y)
 
 	case Imm:
 		addr := c.pc
 		c.pc++
 		return uint16(addr)
 
-	case Imp:
-		return 0
-
 	case Ind:
 		return c.readTwoBytes()
 
 	case Inx:
-		return c.readTwoBytesIndexed(uint16(c.nextByte()) + uint16(c.x))
+		zp := c.nextByte()
+		c.read(uint16(zp))
+		return c.readPointer(zp + c.x)
 
 	case Iny:
-		addr := c.readTwoBytesIndexed(uint16(c.nextByte())) + uint16(c.y)
-		c.checkPage(addr-uint16(c.y), addr)
-		return addr
+		return c.indexed(c.readPointer(c.nextByte()), c.y)
 
 	case Rel:
-		offset := uint16(c.nextByte())
-		var addr uint16
-		if offset < 0x80 {
-			addr = c.pc + offset
-		} else {
-			addr = c.pc + offset - 0x100
-		}
-		return addr
+		offset := c.nextByte()
+		return c.pc + uint16(int8(offset))
 
 	case Zp:
-		return uint16(c.nextByte()) % 256
+		return uint16(c.nextByte())
 
 	case Zpx:
-		return (uint16(c.nextByte()) + uint16(c.x)) % 256
+		zp := c.nextByte()
+		c.read(uint16(zp))
+		return uint16(zp + c.x)
 
 	case Zpy:
-		return (uint16(c.nextByte()) + uint16(c.y)) % 256
+		zp := c.nextByte()
+		c.read(uint16(zp))
+		return uint16(zp + c.y)
 
 	default:
 		return 0
@@ -233,92 +300,201 @@ func (c *CPU) pop16() uint16 {
 	return (hi << 8) | lo
 }
 
-func (c *CPU) stallForDma() {
-	c.stall = 513 + (c.totalCyc % 2)
+// readStack is the read that pulling instructions make while S is
+// incremented.
+func (c *CPU) readStack() {
+	c.read(0x100 | uint16(c.s))
+}
+
+// modify reads, changes and writes back memory. The CPU writes the value it
+// read while it works out the new one.
+func (c *CPU) modify(addr uint16, op func(uint8) uint8) uint8 {
+	val := c.read(addr)
+	c.write(addr, val)
+	val = op(val)
+	c.write(addr, val)
+	return val
+}
+
+// branch jumps to target if taken. A taken branch that stays on the same page
+// doesn't poll interrupts on its last cycle, so one arriving then waits for
+// another instruction.
+func (c *CPU) branch(taken bool, target uint16) {
+	if !taken {
+		return
+	}
+	if (c.pc & 0xFF00) == (target & 0xFF00) {
+		c.nmiPending = c.nmiPending && c.runNmi
+		c.irqPending = c.irqPending && c.runIrq
+		c.read(c.pc)
+	} else {
+		c.read(c.pc)
+		c.read(c.pc&0xFF00 | target&0xFF)
+	}
+	c.pc = target
 }
 
 func (c *CPU) triggerInterrupt(i Interrupt) {
 	c.interrupt = i
 }
 
+func (c *CPU) setIrq(src IrqSource) {
+	c.irq |= src
+}
+
+func (c *CPU) clearIrq(src IrqSource) {
+	c.irq &^= src
+}
+
 func (c *CPU) checkInterrupts() {
-	switch c.interrupt {
-	case Nmi:
-		c.push16(c.pc)
-		php(c, 0)
-		c.pc = (uint16(c.read(0xFFFB)) << 8) | uint16(c.read(0xFFFA))
-		c.p.setInterrupt()
-		c.cyc += 7
+	switch {
+	case c.runNmi:
+		c.interrupt = NoInterrupt
+		c.serviceInterrupt(0xFFFA)
+	case c.runIrq:
+		c.serviceInterrupt(0xFFFE)
 	}
-	c.interrupt = NoInterrupt
 }
 
-func illegal(c *CPU, operand uint16) {
-	if c.debug {
-		os.Exit(0)
+func (c *CPU) serviceInterrupt(vector uint16) {
+	c.read(c.pc)
+	c.read(c.pc)
+	c.push16(c.pc)
+	c.push8(c.p.getStatus()&0xEF | 0x20)
+	c.p.setInterrupt()
+	c.pc = c.readVector(vector)
+}
+
+// readVector reads an interrupt vector. An NMI that arrives while an IRQ or
+// BRK is pushing takes over its vector.
+func (c *CPU) readVector(vector uint16) uint16 {
+	if c.interrupt == Nmi {
+		c.interrupt = NoInterrupt
+		vector = 0xFFFA
 	}
+	lo := c.read(vector)
+	hi := c.read(vector + 1)
+	return (uint16(hi) << 8) | uint16(lo)
 }
 
-func adc(c *CPU, operand uint16) {
+func (c *CPU) addWithCarry(val uint8) {
 	cy := c.p.getCarry()
-	val := uint16(c.read(operand))
-	ans := uint16(c.a) + val + uint16(cy)
+	ans := uint16(c.a) + uint16(val) + uint16(cy)
 	c.p.checkNegative(uint8(ans))
 	c.p.checkZero(uint8(ans))
 	c.p.checkCarry(ans, 0x100)
-	c.p.checkOverflow(uint16(c.a), val, ans)
+	c.p.checkOverflow(uint16(c.a), uint16(val), ans)
 	c.a = uint8(ans)
 }
 
-func and(c *CPU, operand uint16) {
-	c.a &= c.read(operand)
-	c.p.checkNegative(c.a)
-	c.p.checkZero(c.a)
+func (c *CPU) subtractWithBorrow(val uint8) {
+	cy := c.p.getCarry()
+	ans := uint16(c.a) - uint16(val) - uint16(1-cy)
+	c.p.checkNegative(uint8(ans))
+	c.p.checkZero(uint8(ans))
+	c.p.checkBorrow(int(c.a)-int(val)-int(1-cy), 0)
+	c.p.checkUnderflow(uint16(c.a), uint16(val), ans)
+	c.a = uint8(ans)
 }
 
-func asla(c *CPU, operand uint16) {
-	if ((c.a >> 7) & 1) == 1 {
+func (c *CPU) compare(reg, val uint8) {
+	c.p.checkNegative(reg - val)
+	c.p.checkZero(reg - val)
+	c.p.checkCarry(uint16(reg), uint16(val))
+}
+
+func (c *CPU) shiftLeft(val uint8) uint8 {
+	if ((val >> 7) & 1) == 1 {
 		c.p.setCarry()
 	} else {
 		c.p.resetCarry()
 	}
-	c.a <<= 1
-	c.p.checkNegative(c.a)
-	c.p.checkZero(c.a)
+	val <<= 1
+	c.p.checkNegative(val)
+	c.p.checkZero(val)
+	return val
 }
 
-func asl(c *CPU, operand uint16) {
-	val := c.read(operand)
+func (c *CPU) shiftRight(val uint8) uint8 {
+	if (val & 1) == 1 {
+		c.p.setCarry()
+	} else {
+		c.p.resetCarry()
+	}
+	val >>= 1
+	c.p.resetNegative()
+	c.p.checkZero(val)
+	return val
+}
+
+func (c *CPU) rotateLeft(val uint8) uint8 {
+	cy := c.p.getCarry()
 	if ((val >> 7) & 1) == 1 {
 		c.p.setCarry()
 	} else {
 		c.p.resetCarry()
 	}
-	val <<= 1
-	c.write(operand, val)
+	val = (val << 1) | cy
 	c.p.checkNegative(val)
 	c.p.checkZero(val)
+	return val
 }
 
-func bcc(c *CPU, operand uint16) {
-	if c.p.getCarry() == 0 {
-		c.pc = operand
-		c.cyc++
+func (c *CPU) rotateRight(val uint8) uint8 {
+	cy := c.p.getCarry()
+	if (val & 1) == 1 {
+		c.p.setCarry()
+	} else {
+		c.p.resetCarry()
 	}
+	val = (val >> 1) | (cy << 7)
+	c.p.checkNegative(val)
+	c.p.checkZero(val)
+	return val
+}
+
+func (c *CPU) increment(val uint8) uint8 {
+	val++
+	c.p.checkNegative(val)
+	c.p.checkZero(val)
+	return val
+}
+
+func (c *CPU) decrement(val uint8) uint8 {
+	val--
+	c.p.checkNegative(val)
+	c.p.checkZero(val)
+	return val
+}
+
+func adc(c *CPU, operand uint16) {
+	c.addWithCarry(c.read(operand))
+}
+
+func and(c *CPU, operand uint16) {
+	c.a &= c.read(operand)
+	c.p.checkNegative(c.a)
+	c.p.checkZero(c.a)
+}
+
+func asla(c *CPU, operand uint16) {
+	c.a = c.shiftLeft(c.a)
+}
+
+func asl(c *CPU, operand uint16) {
+	c.modify(operand, c.shiftLeft)
+}
+
+func bcc(c *CPU, operand uint16) {
+	c.branch(c.p.getCarry() == 0, operand)
 }
 
 func bcs(c *CPU, operand uint16) {
-	if c.p.getCarry() == 1 {
-		c.pc = operand
-		c.cyc++
-	}
+	c.branch(c.p.getCarry() == 1, operand)
 }
 
 func beq(c *CPU, operand uint16) {
-	if c.p.getZero() == 1 {
-		c.pc = operand
-		c.cyc++
-	}
+	c.branch(c.p.getZero() == 1, operand)
 }
 
 func bit(c *CPU, operand uint16) {
@@ -339,44 +515,30 @@ func bit(c *CPU, operand uint16) {
 }
 
 func bne(c *CPU, operand uint16) {
-	if c.p.getZero() == 0 {
-		c.pc = operand
-		c.cyc++
-	}
+	c.branch(c.p.getZero() == 0, operand)
 }
 
 func bmi(c *CPU, operand uint16) {
-	if c.p.getNegative() == 1 {
-		c.pc = operand
-		c.cyc++
-	}
+	c.branch(c.p.getNegative() == 1, operand)
 }
 
 func bpl(c *CPU, operand uint16) {
-	if c.p.getNegative() == 0 {
-		c.pc = operand
-		c.cyc++
-	}
+	c.branch(c.p.getNegative() == 0, operand)
 }
 
 func brk(c *CPU, operand uint16) {
 	c.push16(c.pc + 1)
+	c.push8(c.p.getStatus() | 0x30)
 	c.p.setInterrupt()
-	c.push8(c.p.getStatus())
+	c.pc = c.readVector(0xFFFE)
 }
 
 func bvc(c *CPU, operand uint16) {
-	if c.p.getOverflow() == 0 {
-		c.pc = operand
-		c.cyc++
-	}
+	c.branch(c.p.getOverflow() == 0, operand)
 }
 
 func bvs(c *CPU, operand uint16) {
-	if c.p.getOverflow() == 1 {
-		c.pc = operand
-		c.cyc++
-	}
+	c.branch(c.p.getOverflow() == 1, operand)
 }
 
 func clc(c *CPU, operand uint16) {
@@ -396,31 +558,19 @@ func clv(c *CPU, operand uint16) {
 }
 
 func cmp(c *CPU, operand uint16) {
-	val := c.a - c.read(operand)
-	c.p.checkNegative(val)
-	c.p.checkZero(val)
-	c.p.checkCarry(uint16(c.a), uint16(c.read(operand)))
+	c.compare(c.a, c.read(operand))
 }
 
 func cpx(c *CPU, operand uint16) {
-	val := c.x - c.read(operand)
-	c.p.checkNegative(val)
-	c.p.checkZero(val)
-	c.p.checkCarry(uint16(c.x), uint16(c.read(operand)))
+	c.compare(c.x, c.read(operand))
 }
 
 func cpy(c *CPU, operand uint16) {
-	val := c.y - c.read(operand)
-	c.p.checkNegative(val)
-	c.p.checkZero(val)
-	c.p.checkCarry(uint16(c.y), uint16(c.read(operand)))
+	c.compare(c.y, c.read(operand))
 }
 
 func dec(c *CPU, operand uint16) {
-	val := c.read(operand) - 1
-	c.write(operand, val)
-	c.p.checkNegative(val)
-	c.p.checkZero(val)
+	c.modify(operand, c.decrement)
 }
 
 func dex(c *CPU, operand uint16) {
@@ -442,10 +592,7 @@ func eor(c *CPU, operand uint16) {
 }
 
 func inc(c *CPU, operand uint16) {
-	val := c.read(operand) + 1
-	c.write(operand, val)
-	c.p.checkNegative(val)
-	c.p.checkZero(val)
+	c.modify(operand, c.increment)
 }
 
 func inx(c *CPU, operand uint16) {
@@ -464,9 +611,14 @@ func jmp(c *CPU, operand uint16) {
 	c.pc = operand
 }
 
+// jsr reads the low byte of its target, pushes the address of the high byte,
+// and only then reads the high byte.
 func jsr(c *CPU, operand uint16) {
-	c.push16(c.pc - 1)
-	c.pc = operand
+	lo := c.read(operand)
+	c.readStack()
+	c.push16(c.pc)
+	hi := c.read(c.pc)
+	c.pc = uint16(hi)<<8 | uint16(lo)
 }
 
 func lda(c *CPU, operand uint16) {
@@ -488,31 +640,20 @@ func ldy(c *CPU, operand uint16) {
 }
 
 func lsra(c *CPU, operand uint16) {
-	if (c.a & 1) == 1 {
-		c.p.setCarry()
-	} else {
-		c.p.resetCarry()
-	}
-	c.a >>= 1
-	c.p.resetNegative()
-	c.p.checkZero(c.a)
+	c.a = c.shiftRight(c.a)
 }
 
 func lsr(c *CPU, operand uint16) {
-	val := c.read(operand)
-	if (val & 1) == 1 {
-		c.p.setCarry()
-	} else {
-		c.p.resetCarry()
-	}
-	val >>= 1
-	c.write(operand, val)
-	c.p.resetNegative()
-	c.p.checkZero(val)
+	c.modify(operand, c.shiftRight)
 }
 
 func nop(c *CPU, operand uint16) {}
 
+// ign is a NOP that still reads its operand.
+func ign(c *CPU, operand uint16) {
+	c.read(operand)
+}
+
 func ora(c *CPU, operand uint16) {
 	c.a |= c.read(operand)
 	c.p.checkNegative(c.a)
@@ -528,12 +669,14 @@ func php(c *CPU, operand uint16) {
 }
 
 func pla(c *CPU, operand uint16) {
+	c.readStack()
 	c.a = c.pop8()
 	c.p.checkNegative(c.a)
 	c.p.checkZero(c.a)
 }
 
 func plp(c *CPU, operand uint16) {
+	c.readStack()
 	bit4 := bits.Value(c.p.getStatus(), 4)
 	bit5 := bits.Value(c.p.getStatus(), 5)
 	c.p.setStatus(c.pop8())
@@ -552,75 +695,36 @@ func plp(c *CPU, operand uint16) {
 }
 
 func rola(c *CPU, operand uint16) {
-	cy := c.p.getCarry()
-	if ((c.a >> 7) & 1) == 1 {
-		c.p.setCarry()
-	} else {
-		c.p.resetCarry()
-	}
-	c.a = (c.a << 1) | cy
-	c.p.checkNegative(c.a)
-	c.p.checkZero(c.a)
+	c.a = c.rotateLeft(c.a)
 }
 
 func rol(c *CPU, operand uint16) {
-	val := c.read(operand)
-	cy := c.p.getCarry()
-	if ((val >> 7) & 1) == 1 {
-		c.p.setCarry()
-	} else {
-		c.p.resetCarry()
-	}
-	val = (val << 1) | cy
-	c.write(operand, val)
-	c.p.checkNegative(val)
-	c.p.checkZero(val)
+	c.modify(operand, c.rotateLeft)
 }
 
 func rora(c *CPU, operand uint16) {
-	cy := c.p.getCarry()
-	if (c.a & 1) == 1 {
-		c.p.setCarry()
-	} else {
-		c.p.resetCarry()
-	}
-	c.a = (c.a >> 1) | (cy << 7)
-	c.p.checkNegative(c.a)
-	c.p.checkZero(c.a)
+	c.a = c.rotateRight(c.a)
 }
 
 func ror(c *CPU, operand uint16) {
-	val := c.read(operand)
-	cy := c.p.getCarry()
-	if (val & 1) == 1 {
-		c.p.setCarry()
-	} else {
-		c.p.resetCarry()
-	}
-	val = (val >> 1) | (cy << 7)
-	c.write(operand, val)
-	c.p.checkNegative(val)
-	c.p.checkZero(val)
+	c.modify(operand, c.rotateRight)
 }
 
 func rti(c *CPU, operand uint16) {
+	c.readStack()
 	c.p.setStatus(c.pop8()&0xEF | 0x20)
 	c.pc = c.pop16()
 }
 
 func rts(c *CPU, operand uint16) {
-	c.pc = c.pop16() + 1
+	c.readStack()
+	c.pc = c.pop16()
+	c.read(c.pc)
+	c.pc++
 }
 
 func sbc(c *CPU, operand uint16) {
-	cy := c.p.getCarry()
-	val := uint16(c.read(operand))
-	ans := uint16(c.a) - val - uint16(1-cy)
-	c.p.checkNegative(uint8(ans))
-	c.p.checkZero(uint8(ans))
-	c.p.checkBorrow(int(c.a)-int(val)-int(1-cy), 0)
-	c.p.checkUnderflow(uint16(c.a), val, ans)
-	c.a = uint8(ans)
+	c.subtractWithBorrow(c.read(operand))
 }
 
 func sec(c *CPU, operand uint16) {
@@ -680,3 +784,177 @@ func tya(c *CPU, operand uint16) {
 	c.p.checkNegative(c.a)
 	c.p.checkZero(c.a)
 }
+
+// The unofficial opcodes below are mostly two official ones sharing an
+// addressing mode, the second working on what the first wrote.
+
+func slo(c *CPU, operand uint16) {
+	c.a |= c.modify(operand, c.shiftLeft)
+	c.p.checkNegative(c.a)
+	c.p.checkZero(c.a)
+}
+
+func rla(c *CPU, operand uint16) {
+	c.a &= c.modify(operand, c.rotateLeft)
+	c.p.checkNegative(c.a)
+	c.p.checkZero(c.a)
+}
+
+func sre(c *CPU, operand uint16) {
+	c.a ^= c.modify(operand, c.shiftRight)
+	c.p.checkNegative(c.a)
+	c.p.checkZero(c.a)
+}
+
+func rra(c *CPU, operand uint16) {
+	c.addWithCarry(c.modify(operand, c.rotateRight))
+}
+
+func dcp(c *CPU, operand uint16) {
+	c.compare(c.a, c.modify(operand, c.decrement))
+}
+
+func isc(c *CPU, operand uint16) {
+	c.subtractWithBorrow(c.modify(operand, c.increment))
+}
+
+func sax(c *CPU, operand uint16) {
+	c.write(operand, c.a&c.x)
+}
+
+func lax(c *CPU, operand uint16) {
+	lda(c, operand)
+	tax(c, operand)
+}
+
+func las(c *CPU, operand uint16) {
+	c.s &= c.read(operand)
+	c.a = c.s
+	tax(c, operand)
+}
+
+func anc(c *CPU, operand uint16) {
+	and(c, operand)
+	if c.p.getNegative() == 1 {
+		c.p.setCarry()
+	} else {
+		c.p.resetCarry()
+	}
+}
+
+func alr(c *CPU, operand uint16) {
+	and(c, operand)
+	lsra(c, operand)
+}
+
+// arr rotates like ROR but sets carry from bit 6 of the result and overflow
+// from bit 6 xor bit 5.
+func arr(c *CPU, operand uint16) {
+	c.a = (c.a&c.read(operand))>>1 | c.p.getCarry()<<7
+	c.p.checkNegative(c.a)
+	c.p.checkZero(c.a)
+
+	if bits.Test(c.a, 6) {
+		c.p.setCarry()
+	} else {
+		c.p.resetCarry()
+	}
+
+	if bits.Value(c.a, 6) != bits.Value(c.a, 5) {
+		c.p.setOverflow()
+	} else {
+		c.p.resetOverflow()
+	}
+}
+
+func axs(c *CPU, operand uint16) {
+	ax := c.a & c.x
+	val := c.read(operand)
+	c.x = ax - val
+	c.p.checkNegative(c.x)
+	c.p.checkZero(c.x)
+	c.p.checkCarry(uint16(ax), uint16(val))
+}
+
+// xaa depends on analogue effects. 0xEE is the most common value of the
+// constant that gets ORed into A.
+func xaa(c *CPU, operand uint16) {
+	c.a = (c.a | 0xEE) & c.x & c.read(operand)
+	c.p.checkNegative(c.a)
+	c.p.checkZero(c.a)
+}
+
+func shy(c *CPU, operand uint16) {
+	c.storeHigh(operand, c.x, c.y)
+}
+
+func shx(c *CPU, operand uint16) {
+	c.storeHigh(operand, c.y, c.x)
+}
+
+func ahx(c *CPU, operand uint16) {
+	c.storeHigh(operand, c.y, c.a&c.x)
+}
+
+func tas(c *CPU, operand uint16) {
+	c.s = c.a & c.x
+	c.storeHigh(operand, c.y, c.s)
+}
+
+// storeHigh stores val ANDed with the high byte of the unindexed address plus
+// one. When indexing crosses a page, that value also replaces the high byte
+// of the address.
+func (c *CPU) storeHigh(addr uint16, index, val uint8) {
+	base := addr - uint16(index)
+	val &= uint8(base>>8) + 1
+	if (base & 0xFF00) != (addr & 0xFF00) {
+		addr = uint16(val)<<8 | addr&0xFF
+	}
+	c.write(addr, val)
+}
+
+// kil locks up the CPU on its own opcode until the NES is reset.
+func kil(c *CPU, operand uint16) {
+	c.pc--
+	c.halted = true
+}
+
+func (c *CPU) save(w *stateWriter) {
+	w.i64(c.totalCyc)
+	w.i64(c.cyc)
+	w.i64(c.stall)
+	w.u8(c.a)
+	w.u8(c.x)
+	w.u8(c.y)
+	w.u8(c.s)
+	w.u16(c.pc)
+	c.p.save(w)
+	w.i64(int(c.interrupt))
+	w.u8(uint8(c.irq))
+	w.bool(c.nmiPending)
+	w.bool(c.irqPending)
+	w.bool(c.runNmi)
+	w.bool(c.runIrq)
+	w.bool(c.halted)
+	c.dma.save(w)
+}
+
+func (c *CPU) load(r *stateReader) {
+	c.totalCyc = r.i64()
+	c.cyc = r.i64()
+	c.stall = r.i64()
+	c.a = r.u8()
+	c.x = r.u8()
+	c.y = r.u8()
+	c.s = r.u8()
+	c.pc = r.u16()
+	c.p.load(r)
+	c.interrupt = Interrupt(r.i64())
+	c.irq = IrqSource(r.u8())
+	c.nmiPending = r.bool()
+	c.irqPending = r.bool()
+	c.runNmi = r.bool()
+	c.runIrq = r.bool()
+	c.halted = r.bool()
+	c.dma.load(r)
+}
@@ -0,0 +1,119 @@
+package emu
+
+const (
+	OAM_DMA_BYTES = 256
+)
+
+// DMA is the 2A03's DMA unit. It copies a page of CPU memory to OAM after a
+// write to $4014 and fetches sample bytes for the DMC. It can only halt the
+// CPU on a read cycle, so it waits out any writes, then takes over the bus
+// until both transfers are done. Reads happen on get (even) cycles and
+// writes on put (odd) cycles, so a transfer that starts on the wrong one
+// spends a cycle aligning.
+type DMA struct {
+	cpu                 *CPU
+	oamRunning          bool
+	oamPage             uint8
+	dmcRunning          bool
+	needHalt, needDummy bool
+}
+
+func NewDMA(c *CPU) *DMA {
+	return &DMA{cpu: c}
+}
+
+func (d *DMA) startOam(page uint8) {
+	d.oamRunning = true
+	d.oamPage = page
+	d.needHalt = true
+}
+
+// startDmc asks for a DMC sample fetch. Besides halting the CPU, the DMC
+// spends a dummy cycle before it reads, though it can share both with an OAM
+// transfer already in progress.
+func (d *DMA) startDmc() {
+	if d.dmcRunning {
+		return
+	}
+	d.dmcRunning = true
+	d.needHalt = true
+	d.needDummy = true
+}
+
+// run takes over the bus when the CPU is about to read from addr, if a
+// transfer is waiting. The CPU repeats that read on every cycle it is halted
+// without one of the DMA's own accesses.
+func (d *DMA) run(addr uint16) {
+	if !d.needHalt {
+		return
+	}
+	c := d.cpu
+	d.read(addr)
+
+	var count int
+	var val uint8
+	for d.dmcRunning || d.oamRunning {
+		get := c.totalCyc%2 == 0
+		switch {
+
+		case get && d.dmcRunning && !d.needHalt && !d.needDummy:
+			dmc := c.bus.apu.dmc
+			dmc.fill(d.read(dmc.curAddr))
+			d.dmcRunning = false
+
+		case get && d.oamRunning:
+			val = d.read(uint16(d.oamPage)<<8 | uint16(count/2))
+			count++
+
+		case !get && d.oamRunning && count%2 == 1:
+			d.write(OAMDATA, val)
+			count++
+			if count == OAM_DMA_BYTES*2 {
+				d.oamRunning = false
+			}
+
+		default:
+			d.read(addr)
+		}
+	}
+}
+
+// Every DMA cycle also counts towards the halt and dummy cycles of a DMC
+// fetch waiting to go.
+func (d *DMA) cycle() {
+	if d.needHalt {
+		d.needHalt = false
+	} else if d.needDummy {
+		d.needDummy = false
+	}
+	d.cpu.tick()
+}
+
+func (d *DMA) read(addr uint16) uint8 {
+	d.cycle()
+	val := d.cpu.bus.read(addr)
+	d.cpu.poll()
+	return val
+}
+
+func (d *DMA) write(addr uint16, val uint8) {
+	d.cycle()
+	d.cpu.bus.write(addr, val)
+	d.cpu.poll()
+}
+
+func (d *DMA) save(w *stateWriter) {
+	w.bool(d.oamRunning)
+	w.u8(d.oamPage)
+	w.bool(d.dmcRunning)
+	w.bool(d.needHalt)
+	w.bool(d.needDummy)
+}
+
+func (d *DMA) load(r *stateReader) {
+	d.oamRunning = r.bool()
+	d.oamPage = r.u8()
+	d.dmcRunning = r.bool()
+	d.needHalt = r.bool()
+	d.needDummy = r.bool()
+}
@@ -0,0 +1,161 @@
+package emu
+
+import "github.com/is386/NESify/emu/bits"
+
+var (
+	DMC_TABLE = [16]uint16{
+		428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54,
+	}
+)
+
+type DMC struct {
+	apu                          *APU
+	irqEnabled, loop             bool
+	timer, timerPeriod           uint16
+	sampleAddr, sampleLength     uint16
+	curAddr, bytesRemaining      uint16
+	buffer, shift, bitsRemaining uint8
+	level                        uint8
+	bufferEmpty, silence         bool
+}
+
+func NewDMC(a *APU) *DMC {
+	return &DMC{
+		apu:           a,
+		timerPeriod:   DMC_TABLE[0],
+		bufferEmpty:   true,
+		silence:       true,
+		bitsRemaining: 8,
+	}
+}
+
+func (d *DMC) writeRegister(reg uint16, val uint8) {
+	switch reg {
+	case 0:
+		d.irqEnabled = bits.Test(val, 7)
+		d.loop = bits.Test(val, 6)
+		d.timerPeriod = DMC_TABLE[val&0x0F]
+		if !d.irqEnabled {
+			d.apu.cpu.clearIrq(IrqDmc)
+		}
+	case 1:
+		d.level = val & 0x7F
+	case 2:
+		d.sampleAddr = 0xC000 | (uint16(val) << 6)
+	case 3:
+		d.sampleLength = (uint16(val) << 4) | 1
+	}
+}
+
+func (d *DMC) setEnabled(enabled bool) {
+	if !enabled {
+		d.bytesRemaining = 0
+	} else if d.bytesRemaining == 0 {
+		d.restart()
+		d.fetch()
+	}
+}
+
+func (d *DMC) restart() {
+	d.curAddr = d.sampleAddr
+	d.bytesRemaining = d.sampleLength
+}
+
+// The memory reader refills the sample buffer from CPU memory, which the DMA
+// unit fetches by halting the CPU.
+func (d *DMC) fetch() {
+	if !d.bufferEmpty || d.bytesRemaining == 0 {
+		return
+	}
+	d.apu.cpu.dma.startDmc()
+}
+
+// fill takes the byte fetched from curAddr, unless the channel was disabled
+// while the DMA unit was waiting to read it.
+func (d *DMC) fill(val uint8) {
+	if d.bytesRemaining == 0 {
+		return
+	}
+	d.buffer = val
+	d.bufferEmpty = false
+	d.curAddr++
+	if d.curAddr == 0 {
+		d.curAddr = 0x8000
+	}
+	d.bytesRemaining--
+	if d.bytesRemaining == 0 {
+		if d.loop {
+			d.restart()
+		} else if d.irqEnabled {
+			d.apu.cpu.setIrq(IrqDmc)
+		}
+	}
+}
+
+func (d *DMC) clockTimer() {
+	if d.timer > 0 {
+		d.timer--
+		return
+	}
+	d.timer = d.timerPeriod - 1
+
+	if !d.silence {
+		if bits.Test(d.shift, 0) {
+			if d.level <= 125 {
+				d.level += 2
+			}
+		} else if d.level >= 2 {
+			d.level -= 2
+		}
+		d.shift >>= 1
+	}
+
+	d.bitsRemaining--
+	if d.bitsRemaining == 0 {
+		d.bitsRemaining = 8
+		d.silence = d.bufferEmpty
+		if !d.bufferEmpty {
+			d.shift = d.buffer
+			d.bufferEmpty = true
+			d.fetch()
+		}
+	}
+}
+
+func (d *DMC) output() uint8 {
+	return d.level
+}
+
+func (d *DMC) save(w *stateWriter) {
+	w.bool(d.irqEnabled)
+	w.bool(d.loop)
+	w.u16(d.timer)
+	w.u16(d.timerPeriod)
+	w.u16(d.sampleAddr)
+	w.u16(d.sampleLength)
+	w.u16(d.curAddr)
+	w.u16(d.bytesRemaining)
+	w.u8(d.buffer)
+	w.u8(d.shift)
+	w.u8(d.bitsRemaining)
+	w.u8(d.level)
+	w.bool(d.bufferEmpty)
+	w.bool(d.silence)
+}
+
+func (d *DMC) load(r *stateReader) {
+	d.irqEnabled = r.bool()
+	d.loop = r.bool()
+	d.timer = r.u16()
+	d.timerPeriod = r.u16()
+	d.sampleAddr = r.u16()
+	d.sampleLength = r.u16()
+	d.curAddr = r.u16()
+	d.bytesRemaining = r.u16()
+	d.buffer = r.u8()
+	d.shift = r.u8()
+	d.bitsRemaining = r.u8()
+	d.level = r.u8()
+	d.bufferEmpty = r.bool()
+	d.silence = r.bool()
+}
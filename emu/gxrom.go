@@ -0,0 +1,69 @@
+package emu
+
+type GxROM struct {
+	prg, chr         []uint8
+	chrRam           bool
+	conflicts        bool
+	prgBank, chrBank int
+	mirroring        Mirroring
+}
+
+func newGxROM(c *Cart) Mapper {
+	return &GxROM{}
+}
+
+func (g *GxROM) loadRom(h *Header, prg, chr []uint8) {
+	g.prg = prg
+	g.chr, g.chrRam = newChr(h, chr)
+	g.conflicts = hasBusConflicts(h, true)
+	g.mirroring = h.mirroring
+}
+
+func (g *GxROM) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		return g.chr[(g.chrBank*0x2000+int(addr))%len(g.chr)]
+
+	case addr >= 0x8000:
+		return g.prg[(g.prgBank*0x8000+int(addr-0x8000))%len(g.prg)]
+
+	default:
+		return 0
+	}
+}
+
+func (g *GxROM) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000 && g.chrRam:
+		g.chr[(g.chrBank*0x2000+int(addr))%len(g.chr)] = val
+
+	case addr >= 0x8000:
+		if g.conflicts {
+			val &= g.read(addr)
+		}
+		g.prgBank = int((val >> 4) & 3)
+		g.chrBank = int(val & 3)
+	}
+}
+
+func (g *GxROM) getMirroring() Mirroring {
+	return g.mirroring
+}
+
+func (g *GxROM) save(w *stateWriter) {
+	if g.chrRam {
+		w.bytes(g.chr)
+	}
+	w.i64(g.prgBank)
+	w.i64(g.chrBank)
+}
+
+func (g *GxROM) load(r *stateReader) {
+	if g.chrRam {
+		r.bytes(g.chr)
+	}
+	g.prgBank = r.index(4)
+	g.chrBank = r.index(4)
+}
@@ -0,0 +1,178 @@
+package emu
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/is386/NESify/emu/bits"
+)
+
+const (
+	HEADER_SIZE   = 0x10
+	TRAINER_SIZE  = 0x200
+	PRG_BANK_SIZE = 0x4000
+	CHR_BANK_SIZE = 0x2000
+	PRG_RAM_SIZE  = 0x2000
+	MAX_ROM_EXP   = 26
+)
+
+var (
+	ErrNotNesFile    = errors.New("not an iNES file")
+	ErrTruncatedFile = errors.New("iNES file is truncated")
+)
+
+type Mirroring int
+
+const (
+	Horizontal Mirroring = iota
+	Vertical
+	SingleScreenA
+	SingleScreenB
+	FourScreen
+)
+
+type ConsoleType int
+
+const (
+	Nes ConsoleType = iota
+	VsSystem
+	Playchoice
+	ExtendedConsole
+)
+
+type Timing int
+
+const (
+	Ntsc Timing = iota
+	Pal
+	MultiRegion
+	Dendy
+)
+
+type Header struct {
+	nes20                    bool
+	prgRomSize, chrRomSize   int
+	prgRamSize, prgNvramSize int
+	chrRamSize, chrNvramSize int
+	mapper                   uint16
+	submapper                uint8
+	mirroring                Mirroring
+	battery, trainer         bool
+	console                  ConsoleType
+	timing                   Timing
+}
+
+func parseHeader(rom []uint8) (*Header, error) {
+	if len(rom) < HEADER_SIZE || string(rom[0:4]) != "NES\x1A" {
+		return nil, ErrNotNesFile
+	}
+
+	flags6, flags7 := rom[6], rom[7]
+	h := &Header{
+		nes20:   (flags7 & 0x0C) == 0x08,
+		mapper:  uint16((flags7 & 0xF0) | (flags6 >> 4)),
+		battery: bits.Test(flags6, 1),
+		trainer: bits.Test(flags6, 2),
+	}
+
+	switch {
+	case bits.Test(flags6, 3):
+		h.mirroring = FourScreen
+	case bits.Test(flags6, 0):
+		h.mirroring = Vertical
+	default:
+		h.mirroring = Horizontal
+	}
+
+	if h.nes20 {
+		h.parseNes20(rom)
+	} else {
+		h.parseINes(rom)
+	}
+
+	if h.prgRomSize < 0 || h.chrRomSize < 0 {
+		return nil, fmt.Errorf("%w: ROM size out of range", ErrNotNesFile)
+	}
+	if h.prgRomSize == 0 {
+		return nil, fmt.Errorf("%w: no PRG-ROM", ErrNotNesFile)
+	}
+	if len(rom) < h.romSize() {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrTruncatedFile, h.romSize(), len(rom))
+	}
+	return h, nil
+}
+
+func (h *Header) parseINes(rom []uint8) {
+	// Old dumping tools wrote garbage such as "DiskDude!" into bytes 7-15, which
+	// corrupts the upper nibble of the mapper number.
+	if rom[12] != 0 || rom[13] != 0 || rom[14] != 0 || rom[15] != 0 {
+		h.mapper &= 0x0F
+	}
+
+	h.prgRomSize = int(rom[4]) * PRG_BANK_SIZE
+	h.chrRomSize = int(rom[5]) * CHR_BANK_SIZE
+
+	ramSize := PRG_RAM_SIZE
+	if rom[8] != 0 {
+		ramSize = int(rom[8]) * PRG_RAM_SIZE
+	}
+	if h.battery {
+		h.prgNvramSize = ramSize
+	} else {
+		h.prgRamSize = ramSize
+	}
+
+	if h.chrRomSize == 0 {
+		h.chrRamSize = CHR_BANK_SIZE
+	}
+
+	if bits.Test(rom[9], 0) {
+		h.timing = Pal
+	}
+}
+
+func (h *Header) parseNes20(rom []uint8) {
+	h.mapper |= uint16(rom[8]&0x0F) << 8
+	h.submapper = rom[8] >> 4
+	h.prgRomSize = romSize(rom[4], rom[9]&0x0F, PRG_BANK_SIZE)
+	h.chrRomSize = romSize(rom[5], rom[9]>>4, CHR_BANK_SIZE)
+	h.prgRamSize = ramSize(rom[10] & 0x0F)
+	h.prgNvramSize = ramSize(rom[10] >> 4)
+	h.chrRamSize = ramSize(rom[11] & 0x0F)
+	h.chrNvramSize = ramSize(rom[11] >> 4)
+	h.console = ConsoleType(rom[7] & 3)
+	h.timing = Timing(rom[12] & 3)
+}
+
+// NES 2.0 stores ROM sizes as a 12-bit bank count, unless the upper nibble is
+// $F, in which case the low byte holds an exponent and multiplier instead.
+// Exponents past MAX_ROM_EXP would overflow, so they give -1.
+func romSize(lsb, msb uint8, bankSize int) int {
+	if msb == 0x0F {
+		exp := lsb >> 2
+		if exp > MAX_ROM_EXP {
+			return -1
+		}
+		mul := int(lsb&3)*2 + 1
+		return (1 << exp) * mul
+	}
+	return (int(msb)<<8 | int(lsb)) * bankSize
+}
+
+func ramSize(shift uint8) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
+
+func (h *Header) prgStart() int {
+	if h.trainer {
+		return HEADER_SIZE + TRAINER_SIZE
+	}
+	return HEADER_SIZE
+}
+
+func (h *Header) romSize() int {
+	return h.prgStart() + h.prgRomSize + h.chrRomSize
+}
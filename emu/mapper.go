@@ -1,7 +1,53 @@
 package emu
 
 type Mapper interface {
-	loadRom(rom []uint8)
+	loadRom(h *Header, prg, chr []uint8)
 	read(addr uint16) uint8
 	write(addr uint16, val uint8)
+	getMirroring() Mirroring
+	save(w *stateWriter)
+	load(r *stateReader)
+}
+
+type batteryBacked interface {
+	getPrgRam() []uint8
+}
+
+type a12Watcher interface {
+	watchA12(addr uint16, clock uint64)
+}
+
+// Mappers that switch PRG-ROM in fixed-size banks need it to be a whole number
+// of them, which NES 2.0's exponent sizes don't promise.
+type prgBanked interface {
+	prgBankSize() int
+}
+
+func newChr(h *Header, chr []uint8) ([]uint8, bool) {
+	if len(chr) > 0 {
+		return chr, false
+	}
+	size := h.chrRamSize + h.chrNvramSize
+	if size == 0 {
+		size = CHR_BANK_SIZE
+	}
+	return make([]uint8, size), true
+}
+
+func newPrgRam(h *Header) []uint8 {
+	return make([]uint8, h.prgRamSize+h.prgNvramSize)
+}
+
+// Discrete-logic boards with bus conflicts see the ROM drive the data bus at the
+// same time as the CPU, so a register write only keeps the bits both agree on.
+// NES 2.0 submapper 1 marks a board without conflicts and 2 one with them.
+func hasBusConflicts(h *Header, fallback bool) bool {
+	switch {
+	case h.nes20 && h.submapper == 1:
+		return false
+	case h.nes20 && h.submapper == 2:
+		return true
+	default:
+		return fallback
+	}
 }
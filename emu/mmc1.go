@@ -0,0 +1,190 @@
+package emu
+
+import "github.com/is386/NESify/emu/bits"
+
+type MMC1 struct {
+	prg, chr, prgRam            []uint8
+	chrRam                      bool
+	shift, control              uint8
+	chrBank0, chrBank1, prgBank uint8
+	prgOffsets                  [2]int
+	chrOffsets                  [2]int
+	mirroring                   Mirroring
+}
+
+func newMMC1(c *Cart) Mapper {
+	return &MMC1{}
+}
+
+func (m *MMC1) loadRom(h *Header, prg, chr []uint8) {
+	m.prg = prg
+	m.chr, m.chrRam = newChr(h, chr)
+	m.prgRam = newPrgRam(h)
+	m.shift = 0x10
+	m.writeControl(0x0C)
+}
+
+func (m *MMC1) prgBankSize() int {
+	return PRG_BANK_SIZE
+}
+
+func (m *MMC1) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		bank := addr / 0x1000
+		return m.chr[(m.chrOffsets[bank]+int(addr%0x1000))%len(m.chr)]
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.prgRamEnabled() {
+			return m.prgRam[int(addr-0x6000)%len(m.prgRam)]
+		}
+		return 0
+
+	case addr >= 0x8000:
+		bank := (addr - 0x8000) / 0x4000
+		return m.prg[m.prgOffsets[bank]+int(addr%0x4000)]
+
+	default:
+		return 0
+	}
+}
+
+func (m *MMC1) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000:
+		if m.chrRam {
+			bank := addr / 0x1000
+			m.chr[(m.chrOffsets[bank]+int(addr%0x1000))%len(m.chr)] = val
+		}
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.prgRamEnabled() {
+			m.prgRam[int(addr-0x6000)%len(m.prgRam)] = val
+		}
+
+	case addr >= 0x8000:
+		m.writeShift(addr, val)
+	}
+}
+
+func (m *MMC1) getMirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *MMC1) getPrgRam() []uint8 {
+	return m.prgRam
+}
+
+func (m *MMC1) writeShift(addr uint16, val uint8) {
+	if bits.Test(val, 7) {
+		m.shift = 0x10
+		m.writeControl(m.control | 0x0C)
+		return
+	}
+
+	done := bits.Test(m.shift, 0)
+	m.shift = (m.shift >> 1) | ((val & 1) << 4)
+	if !done {
+		return
+	}
+
+	switch {
+	case addr < 0xA000:
+		m.writeControl(m.shift)
+	case addr < 0xC000:
+		m.chrBank0 = m.shift
+	case addr < 0xE000:
+		m.chrBank1 = m.shift
+	default:
+		m.prgBank = m.shift
+	}
+	m.shift = 0x10
+	m.updateOffsets()
+}
+
+func (m *MMC1) writeControl(val uint8) {
+	m.control = val
+	switch val & 3 {
+	case 0:
+		m.mirroring = SingleScreenA
+	case 1:
+		m.mirroring = SingleScreenB
+	case 2:
+		m.mirroring = Vertical
+	case 3:
+		m.mirroring = Horizontal
+	}
+	m.updateOffsets()
+}
+
+func (m *MMC1) prgRamEnabled() bool {
+	return len(m.prgRam) > 0 && !bits.Test(m.prgBank, 4)
+}
+
+func (m *MMC1) updateOffsets() {
+	// SUROM and friends reuse CHR bank bit 4 to select a 256KB half of PRG-ROM.
+	outer := 0
+	if len(m.prg) > 0x40000 {
+		outer = int(m.chrBank0&0x10) << 14
+	}
+	bank := int(m.prgBank & 0x0F)
+	last := (0x40000 / 0x4000) - 1
+	if len(m.prg) < 0x40000 {
+		last = len(m.prg)/0x4000 - 1
+	}
+
+	switch (m.control >> 2) & 3 {
+	case 0, 1:
+		m.prgOffsets[0] = m.prgOffset(outer, bank&0x0E)
+		m.prgOffsets[1] = m.prgOffset(outer, bank|0x01)
+	case 2:
+		m.prgOffsets[0] = m.prgOffset(outer, 0)
+		m.prgOffsets[1] = m.prgOffset(outer, bank)
+	case 3:
+		m.prgOffsets[0] = m.prgOffset(outer, bank)
+		m.prgOffsets[1] = m.prgOffset(outer, last)
+	}
+
+	if bits.Test(m.control, 4) {
+		m.chrOffsets[0] = m.chrOffset(int(m.chrBank0))
+		m.chrOffsets[1] = m.chrOffset(int(m.chrBank1))
+	} else {
+		m.chrOffsets[0] = m.chrOffset(int(m.chrBank0 & 0x1E))
+		m.chrOffsets[1] = m.chrOffset(int(m.chrBank0 | 0x01))
+	}
+}
+
+func (m *MMC1) prgOffset(outer, bank int) int {
+	return (outer + bank*0x4000) % len(m.prg)
+}
+
+func (m *MMC1) chrOffset(bank int) int {
+	return (bank * 0x1000) % len(m.chr)
+}
+
+func (m *MMC1) save(w *stateWriter) {
+	if m.chrRam {
+		w.bytes(m.chr)
+	}
+	w.bytes(m.prgRam)
+	w.u8(m.shift)
+	w.u8(m.control)
+	w.u8(m.chrBank0)
+	w.u8(m.chrBank1)
+	w.u8(m.prgBank)
+}
+
+func (m *MMC1) load(r *stateReader) {
+	if m.chrRam {
+		r.bytes(m.chr)
+	}
+	r.bytes(m.prgRam)
+	m.shift = r.u8()
+	m.writeControl(r.u8())
+	m.chrBank0 = r.u8()
+	m.chrBank1 = r.u8()
+	m.prgBank = r.u8()
+	m.updateOffsets()
+}
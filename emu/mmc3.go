@@ -0,0 +1,237 @@
+package emu
+
+import "github.com/is386/NESify/emu/bits"
+
+const (
+	// A12 has to stay low for roughly three CPU cycles before a rise clocks the
+	// scanline counter.
+	A12_FILTER = 9
+)
+
+type MMC3 struct {
+	cart                       *Cart
+	prg, chr, prgRam           []uint8
+	chrRam                     bool
+	registers                  [8]uint8
+	bankSelect                 uint8
+	prgOffsets                 [4]int
+	chrOffsets                 [8]int
+	mirroring                  Mirroring
+	prgRamEnabled, prgRamWrite bool
+	irqLatch, irqCounter       uint8
+	irqReload, irqEnabled      bool
+	a12High                    bool
+	a12LowSince                uint64
+}
+
+func newMMC3(c *Cart) Mapper {
+	return &MMC3{cart: c}
+}
+
+func (m *MMC3) loadRom(h *Header, prg, chr []uint8) {
+	m.prg = prg
+	m.chr, m.chrRam = newChr(h, chr)
+	m.prgRam = newPrgRam(h)
+	m.mirroring = h.mirroring
+	m.prgRamEnabled = true
+	m.prgRamWrite = true
+	m.updateOffsets()
+}
+
+func (m *MMC3) prgBankSize() int {
+	return 0x2000
+}
+
+func (m *MMC3) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		bank := addr / 0x400
+		return m.chr[(m.chrOffsets[bank]+int(addr%0x400))%len(m.chr)]
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.prgRamEnabled && len(m.prgRam) > 0 {
+			return m.prgRam[int(addr-0x6000)%len(m.prgRam)]
+		}
+		return 0
+
+	case addr >= 0x8000:
+		bank := (addr - 0x8000) / 0x2000
+		return m.prg[m.prgOffsets[bank]+int(addr%0x2000)]
+
+	default:
+		return 0
+	}
+}
+
+func (m *MMC3) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000:
+		if m.chrRam {
+			bank := addr / 0x400
+			m.chr[(m.chrOffsets[bank]+int(addr%0x400))%len(m.chr)] = val
+		}
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.prgRamEnabled && m.prgRamWrite && len(m.prgRam) > 0 {
+			m.prgRam[int(addr-0x6000)%len(m.prgRam)] = val
+		}
+
+	case addr >= 0x8000:
+		m.writeRegister(addr, val)
+	}
+}
+
+func (m *MMC3) getMirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *MMC3) getPrgRam() []uint8 {
+	return m.prgRam
+}
+
+func (m *MMC3) writeRegister(addr uint16, val uint8) {
+	even := addr%2 == 0
+	switch {
+
+	case addr < 0xA000 && even:
+		m.bankSelect = val
+		m.updateOffsets()
+
+	case addr < 0xA000:
+		m.registers[m.bankSelect&7] = val
+		m.updateOffsets()
+
+	case addr < 0xC000 && even:
+		if m.mirroring == FourScreen {
+			return
+		}
+		if bits.Test(val, 0) {
+			m.mirroring = Horizontal
+		} else {
+			m.mirroring = Vertical
+		}
+
+	case addr < 0xC000:
+		m.prgRamEnabled = bits.Test(val, 7)
+		m.prgRamWrite = !bits.Test(val, 6)
+
+	case addr < 0xE000 && even:
+		m.irqLatch = val
+
+	case addr < 0xE000:
+		m.irqCounter = 0
+		m.irqReload = true
+
+	case even:
+		m.irqEnabled = false
+		m.cart.cpu.clearIrq(IrqMapper)
+
+	default:
+		m.irqEnabled = true
+	}
+}
+
+func (m *MMC3) watchA12(addr uint16, clock uint64) {
+	high := bits.Test(uint8(addr>>8), 4)
+	if high && !m.a12High && clock-m.a12LowSince >= A12_FILTER {
+		m.clockScanline()
+	}
+	if !high && m.a12High {
+		m.a12LowSince = clock
+	}
+	m.a12High = high
+}
+
+func (m *MMC3) clockScanline() {
+	if m.irqCounter == 0 || m.irqReload {
+		m.irqCounter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.irqCounter--
+	}
+	if m.irqCounter == 0 && m.irqEnabled {
+		m.cart.cpu.setIrq(IrqMapper)
+	}
+}
+
+func (m *MMC3) updateOffsets() {
+	last := len(m.prg)/0x2000 - 1
+	if bits.Test(m.bankSelect, 6) {
+		m.prgOffsets[0] = m.prgOffset(last - 1)
+		m.prgOffsets[2] = m.prgOffset(int(m.registers[6]))
+	} else {
+		m.prgOffsets[0] = m.prgOffset(int(m.registers[6]))
+		m.prgOffsets[2] = m.prgOffset(last - 1)
+	}
+	m.prgOffsets[1] = m.prgOffset(int(m.registers[7]))
+	m.prgOffsets[3] = m.prgOffset(last)
+
+	// With CHR inversion set, the two 2KB banks and four 1KB banks swap halves.
+	invert := 0
+	if bits.Test(m.bankSelect, 7) {
+		invert = 4
+	}
+	m.chrOffsets[0^invert] = m.chrOffset(int(m.registers[0] & 0xFE))
+	m.chrOffsets[1^invert] = m.chrOffset(int(m.registers[0] | 0x01))
+	m.chrOffsets[2^invert] = m.chrOffset(int(m.registers[1] & 0xFE))
+	m.chrOffsets[3^invert] = m.chrOffset(int(m.registers[1] | 0x01))
+	m.chrOffsets[4^invert] = m.chrOffset(int(m.registers[2]))
+	m.chrOffsets[5^invert] = m.chrOffset(int(m.registers[3]))
+	m.chrOffsets[6^invert] = m.chrOffset(int(m.registers[4]))
+	m.chrOffsets[7^invert] = m.chrOffset(int(m.registers[5]))
+}
+
+func (m *MMC3) prgOffset(bank int) int {
+	return (bank * 0x2000) % len(m.prg)
+}
+
+func (m *MMC3) chrOffset(bank int) int {
+	return (bank * 0x400) % len(m.chr)
+}
+
+func (m *MMC3) save(w *stateWriter) {
+	if m.chrRam {
+		w.bytes(m.chr)
+	}
+	w.bytes(m.prgRam)
+	w.bytes(m.registers[:])
+	w.u8(m.bankSelect)
+	w.i64(int(m.mirroring))
+	w.bool(m.prgRamEnabled)
+	w.bool(m.prgRamWrite)
+	w.u8(m.irqLatch)
+	w.u8(m.irqCounter)
+	w.bool(m.irqReload)
+	w.bool(m.irqEnabled)
+	w.bool(m.a12High)
+	w.u64(m.a12LowSince)
+}
+
+func (m *MMC3) load(r *stateReader) {
+	if m.chrRam {
+		r.bytes(m.chr)
+	}
+	r.bytes(m.prgRam)
+	r.bytes(m.registers[:])
+	m.bankSelect = r.u8()
+	// Four-screen carts keep their mirroring, and the rest can only switch
+	// between horizontal and vertical.
+	mirroring := Mirroring(r.i64())
+	if m.cart.header.mirroring == FourScreen {
+		r.check(mirroring == FourScreen, "mirroring")
+	} else {
+		r.check(mirroring == Horizontal || mirroring == Vertical, "mirroring")
+	}
+	m.mirroring = mirroring
+	m.prgRamEnabled = r.bool()
+	m.prgRamWrite = r.bool()
+	m.irqLatch = r.u8()
+	m.irqCounter = r.u8()
+	m.irqReload = r.bool()
+	m.irqEnabled = r.bool()
+	m.a12High = r.bool()
+	m.a12LowSince = r.u64()
+	m.updateOffsets()
+}
@@ -2,66 +2,163 @@ package emu
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
-	"time"
 )
 
 const (
 	CLOCK_SPEED = 1789773
-	FPS         = 60
-	FRAMETIME   = time.Second / time.Duration(FPS)
-	CPS         = CLOCK_SPEED / FPS
 )
 
+// Debug traces every instruction to stdout in the format of nestest.log, or
+// to Trace if it is set.
+type Options struct {
+	Debug bool
+	Trace io.Writer
+}
+
 type NES struct {
-	cpu            *CPU
-	ppu            *PPU
-	controllers    *Controllers
-	cyc            int
-	running, debug bool
+	cart        *Cart
+	cpu         *CPU
+	ppu         *PPU
+	apu         *APU
+	controllers *Controllers
 }
 
-func NewNES(romFileName string, debug bool) *NES {
-	nes := &NES{debug: debug}
-	rom := nes.loadRom(romFileName)
-	cart := NewCart(rom)
+func New(rom []uint8, opts Options) (*NES, error) {
+	cart, err := NewCart(rom)
+	if err != nil {
+		return nil, err
+	}
+
+	nes := &NES{cart: cart}
 	nes.controllers = NewControllers()
 	nes.ppu = NewPPU(NewPpuBus(cart))
-	nes.cpu = NewCPU(NewCpuBus(cart, nes.ppu, nes.controllers), debug)
+	nes.apu = NewAPU()
+	trace := opts.Trace
+	if opts.Debug && trace == nil {
+		trace = os.Stdout
+	}
+	nes.cpu = NewCPU(NewCpuBus(cart, nes.ppu, nes.apu, nes.controllers), trace)
 	nes.ppu.cpu = nes.cpu
-	return nes
+	nes.apu.cpu = nes.cpu
+	cart.cpu = nes.cpu
+	return nes, nil
+}
+
+func NewFromReader(r io.Reader, opts Options) (*NES, error) {
+	rom, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return New(rom, opts)
 }
 
-func (nes *NES) Run() {
-	ticker := time.NewTicker(FRAMETIME)
-	nes.running = true
+func (nes *NES) Cart() *Cart {
+	return nes.cart
+}
+
+// StepInstruction runs one CPU instruction, along with any DMA that halts it,
+// or one cycle while the CPU is resetting or halted. The PPU and APU run
+// alongside it a cycle at a time, so they see each of its bus accesses when it
+// happens. It returns the number of CPU cycles taken.
+func (nes *NES) StepInstruction() int {
+	return nes.cpu.update()
+}
+
+// Reset presses the console's reset button. The cart keeps its state, as it
+// does on hardware.
+func (nes *NES) Reset() {
+	nes.cpu.reset()
+	nes.ppu.reset()
+	nes.apu.reset()
+}
 
-	for range ticker.C {
-		if !nes.running {
-			return
-		}
-		nes.update()
+// Halted returns an error wrapping ErrCpuHalted once the CPU has run a KIL
+// opcode. The PPU and APU keep running, so frames still come out.
+func (nes *NES) Halted() error {
+	if !nes.cpu.halted {
+		return nil
 	}
+	pc := nes.cpu.pc
+	return fmt.Errorf("%w: opcode $%02X at $%04X", ErrCpuHalted, nes.PeekCPU(pc), pc)
 }
 
-func (nes *NES) loadRom(romFileName string) []uint8 {
-	rom, err := ioutil.ReadFile(romFileName)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(0)
+// StepFrame runs until the PPU finishes the current frame.
+func (nes *NES) StepFrame() {
+	frame := nes.ppu.frame
+	for nes.ppu.frame == frame {
+		nes.StepInstruction()
 	}
-	return rom
 }
 
-func (nes *NES) update() {
-	for nes.cyc < CPS {
-		cpuCyc := nes.cpu.update()
-		nes.cyc += cpuCyc
-		for i := 0; i < cpuCyc*3; i++ {
-			nes.ppu.update()
-		}
+func (nes *NES) Frame() uint64 {
+	return nes.ppu.frame
+}
+
+func (nes *NES) SetVideoSink(sink VideoSink) {
+	nes.ppu.sink = sink
+}
+
+// SetPalette changes the colours used for frames rendered from now on.
+func (nes *NES) SetPalette(p *Palette) {
+	nes.ppu.palette = p
+}
+
+// Framebuffer returns the PPU's NES_WIDTH*NES_HEIGHT pixels in 0xRRGGBB form.
+// After StepFrame it holds the finished frame. The slice is owned by the PPU
+// and is overwritten as emulation continues.
+func (nes *NES) Framebuffer() []uint32 {
+	return nes.ppu.pixels[:]
+}
+
+// Indices is Framebuffer before the palette is applied: each pixel is a
+// palette index with the three PPUMASK emphasis bits above it.
+func (nes *NES) Indices() []uint16 {
+	return nes.ppu.indices[:]
+}
+
+// AudioSamples returns the mono samples produced since the last call, at the
+// APU sample rate.
+func (nes *NES) AudioSamples() []float32 {
+	return nes.apu.takeSamples()
+}
+
+func (nes *NES) SetSampleRate(rate int) {
+	nes.apu.setSampleRate(rate)
+}
+
+// SetButton presses or releases a button on controller port 0 or 1. Ports and
+// buttons that don't exist are ignored.
+func (nes *NES) SetButton(port int, b Button, pressed bool) {
+	nes.controllers.setButton(port, b, pressed)
+}
+
+// PeekCPU reads CPU memory without side effects. PPU, APU and controller
+// registers read as 0.
+func (nes *NES) PeekCPU(addr uint16) uint8 {
+	return nes.cpu.bus.peek(addr)
+}
+
+// PokeCPU writes CPU memory exactly like the CPU would, including register
+// side effects.
+func (nes *NES) PokeCPU(addr uint16, val uint8) {
+	nes.cpu.bus.write(addr, val)
+}
+
+func (nes *NES) PeekPPU(addr uint16) uint8 {
+	return nes.ppu.bus.peek(addr)
+}
+
+func (nes *NES) PokePPU(addr uint16, val uint8) {
+	nes.ppu.bus.write(addr, val)
+}
+
+// BatteryRAM returns the cart's battery-backed PRG-RAM, or nil if it has none.
+func (nes *NES) BatteryRAM() []uint8 {
+	if !nes.cart.hasBattery() {
+		return nil
 	}
-	nes.running = nes.controllers.update()
-	nes.cyc -= CPS
+	return nes.cart.getPrgRam()
 }
@@ -0,0 +1,63 @@
+package emu
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const (
+	NESTEST_ROM     = "testdata/nestest.nes"
+	NESTEST_LOG     = "testdata/nestest.log"
+	NESTEST_CONTEXT = 5
+)
+
+// TestNestest runs nestest.nes in automation mode, starting at $C000 instead
+// of the reset vector, and compares the trace with the golden log line by
+// line. The ROM and log are not part of the repository; copy them into
+// testdata, as the test fails without them.
+func TestNestest(t *testing.T) {
+	rom, err := ioutil.ReadFile(NESTEST_ROM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := ioutil.ReadFile(NESTEST_LOG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Split(strings.TrimSpace(strings.ReplaceAll(string(log), "\r\n", "\n")), "\n")
+
+	var trace bytes.Buffer
+	nes, err := New(rom, Options{Trace: &trace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nes.cpu.pc = 0xC000
+
+	for i := 0; i < len(want); {
+		if err := nes.Halted(); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		nes.StepInstruction()
+		if trace.Len() == 0 {
+			continue
+		}
+
+		got := strings.TrimRight(trace.String(), "\n")
+		trace.Reset()
+		if got != strings.TrimRight(want[i], " ") {
+			start := i - NESTEST_CONTEXT
+			if start < 0 {
+				start = 0
+			}
+			t.Fatalf("trace diverges at line %d\n%s\nwant %s\ngot  %s",
+				i+1, strings.Join(want[start:i], "\n"), want[i], got)
+		}
+		i++
+	}
+
+	if nes.PeekCPU(0x02) != 0 || nes.PeekCPU(0x03) != 0 {
+		t.Errorf("nestest reported error $%02X%02X", nes.PeekCPU(0x02), nes.PeekCPU(0x03))
+	}
+}
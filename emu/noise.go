@@ -0,0 +1,93 @@
+package emu
+
+import "github.com/is386/NESify/emu/bits"
+
+var (
+	NOISE_TABLE = [16]uint16{
+		4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068,
+	}
+)
+
+type Noise struct {
+	enabled, lengthHalt, mode bool
+	length                    uint8
+	timer, timerPeriod, shift uint16
+	envelope                  Envelope
+}
+
+func NewNoise() *Noise {
+	return &Noise{shift: 1}
+}
+
+func (n *Noise) writeRegister(reg uint16, val uint8) {
+	switch reg {
+	case 0:
+		n.lengthHalt = bits.Test(val, 5)
+		n.envelope.write(val)
+	case 2:
+		n.mode = bits.Test(val, 7)
+		n.timerPeriod = NOISE_TABLE[val&0x0F]
+	case 3:
+		if n.enabled {
+			n.length = LENGTH_TABLE[val>>3]
+		}
+		n.envelope.start = true
+	}
+}
+
+func (n *Noise) setEnabled(enabled bool) {
+	n.enabled = enabled
+	if !enabled {
+		n.length = 0
+	}
+}
+
+func (n *Noise) clockTimer() {
+	if n.timer > 0 {
+		n.timer--
+		return
+	}
+	n.timer = n.timerPeriod
+
+	tap := uint16(1)
+	if n.mode {
+		tap = 6
+	}
+	feedback := (n.shift & 1) ^ ((n.shift >> tap) & 1)
+	n.shift = (n.shift >> 1) | (feedback << 14)
+}
+
+func (n *Noise) clockLength() {
+	if n.length > 0 && !n.lengthHalt {
+		n.length--
+	}
+}
+
+func (n *Noise) output() uint8 {
+	if n.length == 0 || (n.shift&1) == 1 {
+		return 0
+	}
+	return n.envelope.output()
+}
+
+func (n *Noise) save(w *stateWriter) {
+	w.bool(n.enabled)
+	w.bool(n.lengthHalt)
+	w.bool(n.mode)
+	w.u8(n.length)
+	w.u16(n.timer)
+	w.u16(n.timerPeriod)
+	w.u16(n.shift)
+	n.envelope.save(w)
+}
+
+func (n *Noise) load(r *stateReader) {
+	n.enabled = r.bool()
+	n.lengthHalt = r.bool()
+	n.mode = r.bool()
+	n.length = r.u8()
+	n.timer = r.u16()
+	n.timerPeriod = r.u16()
+	n.shift = r.u16()
+	n.envelope.load(r)
+}
@@ -1,53 +1,68 @@
 package emu
 
 type NROM struct {
-	rom  [0x8000]uint8
-	chr  [0x4000]uint8
-	size int
+	prg, chr, prgRam []uint8
+	chrRam           bool
+	mirroring        Mirroring
 }
 
-func newNROM() Mapper {
+func newNROM(c *Cart) Mapper {
 	return &NROM{}
 }
 
-func (n *NROM) loadRom(rom []uint8) {
-	n.size = len(rom)
-	end := uint16(len(rom) - 0x2010)
-	for i := uint16(0); i < end; i++ {
-		n.rom[i] = rom[i+0x10]
-	}
-	start := uint16(len(rom) - 0x2000)
-	end = uint16(len(rom))
-	for i := start; i < end; i++ {
-		n.chr[i-start] = rom[i]
-	}
+func (n *NROM) loadRom(h *Header, prg, chr []uint8) {
+	n.prg = prg
+	n.chr, n.chrRam = newChr(h, chr)
+	n.prgRam = newPrgRam(h)
+	n.mirroring = h.mirroring
 }
 
 func (n *NROM) read(addr uint16) uint8 {
-	if addr < 0x2000 {
-		return n.chr[addr]
-	} else if addr >= 0x8000 && addr <= 0xBFFF {
-		return n.rom[addr-0x8000]
-	} else if addr >= 0xC000 && addr <= 0xFFFF {
-		if n.size == 0xA010 {
-			return n.rom[addr-0x8000]
-		} else {
-			return n.rom[addr-0xC000]
-		}
+	switch {
+
+	case addr < 0x2000:
+		return n.chr[int(addr)%len(n.chr)]
+
+	case addr >= 0x6000 && addr < 0x8000 && len(n.prgRam) > 0:
+		return n.prgRam[int(addr-0x6000)%len(n.prgRam)]
+
+	case addr >= 0x8000:
+		return n.prg[int(addr-0x8000)%len(n.prg)]
+
+	default:
+		return 0
 	}
-	return 0
 }
 
 func (n *NROM) write(addr uint16, val uint8) {
-	if addr < 0x2000 {
-		n.chr[addr] = val
-	} else if addr >= 0x8000 && addr <= 0xBFFF {
-		n.rom[addr-0x8000] = val
-	} else if addr >= 0xC000 && addr <= 0xFFFF {
-		if n.size == 0xA010 {
-			n.rom[addr-0x8000] = val
-		} else {
-			n.rom[addr-0xC000] = val
-		}
+	switch {
+
+	case addr < 0x2000 && n.chrRam:
+		n.chr[int(addr)%len(n.chr)] = val
+
+	case addr >= 0x6000 && addr < 0x8000 && len(n.prgRam) > 0:
+		n.prgRam[int(addr-0x6000)%len(n.prgRam)] = val
+	}
+}
+
+func (n *NROM) getMirroring() Mirroring {
+	return n.mirroring
+}
+
+func (n *NROM) getPrgRam() []uint8 {
+	return n.prgRam
+}
+
+func (n *NROM) save(w *stateWriter) {
+	if n.chrRam {
+		w.bytes(n.chr)
+	}
+	w.bytes(n.prgRam)
+}
+
+func (n *NROM) load(r *stateReader) {
+	if n.chrRam {
+		r.bytes(n.chr)
 	}
+	r.bytes(n.prgRam)
 }
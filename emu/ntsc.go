@@ -0,0 +1,150 @@
+package emu
+
+import (
+	"math"
+)
+
+const (
+	NTSC_WIDTH   = NES_WIDTH * 2
+	NTSC_RADIUS  = 2
+	NTSC_SAMPLES = 8
+	GAMMA_STEPS  = 1024
+)
+
+var (
+	// Composite luma spans two subcarrier cycles so that flat colours come out
+	// clean and only edges pick up artifacts.
+	NTSC_COMPOSITE = NtscFilterSettings{NtscSettings: DEFAULT_NTSC, LumaWidth: 24, ChromaWidth: 24}
+	NTSC_SVIDEO    = NtscFilterSettings{NtscSettings: DEFAULT_NTSC, LumaWidth: 6, ChromaWidth: 24, Separated: true}
+	NTSC_RGB       = NtscFilterSettings{NtscSettings: DEFAULT_NTSC, LumaWidth: 4, ChromaWidth: 4, Separated: true}
+)
+
+// NtscFilterSettings describes the video connection being emulated. Widths
+// are in PPU samples, eight to a pixel, and set how far luma and chroma blur.
+// Composite decodes both from the one signal, so chroma leaks into luma as
+// fringing and dot crawl. Separated connections keep them apart.
+type NtscFilterSettings struct {
+	NtscSettings
+	LumaWidth, ChromaWidth float64
+	Separated              bool
+}
+
+// NtscFilter turns palette indices into NTSC_WIDTH*NES_HEIGHT pixels as they
+// would come out of a TV decoding the PPU's video signal.
+//
+// Decoding is linear, so each output pixel is the sum of what its neighbouring
+// input pixels contribute. Those contributions only depend on the input colour,
+// its subcarrier phase and its distance, and are worked out up front.
+type NtscFilter struct {
+	kernel [3][2][NTSC_RADIUS*2 + 1][PALETTE_COLORS * 8][3]float32
+	gamma  [GAMMA_STEPS + 1]uint32
+}
+
+func NewNtscFilter(s NtscFilterSettings) *NtscFilter {
+	f := &NtscFilter{}
+	for i := range f.gamma {
+		f.gamma[i] = gammaCorrect(float64(i)/GAMMA_STEPS, s.Gamma)
+	}
+
+	lumaNorm := windowSum(s.LumaWidth)
+	chromaNorm := windowSum(s.ChromaWidth)
+	for pixel := uint16(0); pixel < PALETTE_COLORS*8; pixel++ {
+		y, i, q := ntscDecode(pixel, s.Hue)
+		for phase := 0; phase < 3; phase++ {
+			for sub := 0; sub < 2; sub++ {
+				for rel := -NTSC_RADIUS; rel <= NTSC_RADIUS; rel++ {
+					var ky, ki, kq float64
+					for k := 0; k < NTSC_SAMPLES; k++ {
+						d := float64(rel*NTSC_SAMPLES+k) - float64(sub*NTSC_SAMPLES/2) - 1.5
+						wy := window(d, s.LumaWidth) / lumaNorm
+						wc := window(d, s.ChromaWidth) / chromaNorm
+						if s.Separated {
+							ky += y * wy
+							ki += i * wc
+							kq += q * wc
+							continue
+						}
+
+						p := (phase*4 + k) % 12
+						signal := ntscSignal(pixel, p)
+						angle := ntscAngle(p, s.Hue)
+						ky += signal * wy
+						ki += signal * math.Cos(angle) * wc
+						kq += signal * math.Sin(angle) * wc
+					}
+
+					chroma := s.Contrast * s.Saturation
+					c := yiqToRgb(ky*s.Contrast, ki*chroma, kq*chroma)
+					out := &f.kernel[phase][sub][rel+NTSC_RADIUS][pixel]
+					for ch := range out {
+						out[ch] = float32(c[ch])
+					}
+				}
+			}
+		}
+	}
+	return f
+}
+
+// Filter decodes one frame of indices, as returned by NES.Indices, into out.
+// Each line starts a third of a subcarrier cycle later than the last, and the
+// starting phase moves every frame, which is what makes the artifacts crawl.
+func (f *NtscFilter) Filter(indices []uint16, frame uint64, out []uint32) {
+	var phases [NES_WIDTH]uint8
+	for y := 0; y < NES_HEIGHT; y++ {
+		line := indices[y*NES_WIDTH : (y+1)*NES_WIDTH]
+		start := int(frame%3) + y
+		for x := range phases {
+			phases[x] = uint8((start + 2*x) % 3)
+		}
+
+		for x := 0; x < NES_WIDTH; x++ {
+			for sub := 0; sub < 2; sub++ {
+				var r, g, b float32
+				for rel := -NTSC_RADIUS; rel <= NTSC_RADIUS; rel++ {
+					j := x + rel
+					if j < 0 || j >= NES_WIDTH {
+						continue
+					}
+					k := &f.kernel[phases[j]][sub][rel+NTSC_RADIUS][line[j]]
+					r += k[0]
+					g += k[1]
+					b += k[2]
+				}
+				out[y*NTSC_WIDTH+x*2+sub] = f.channel(r)<<16 | f.channel(g)<<8 | f.channel(b)
+			}
+		}
+	}
+}
+
+func (f *NtscFilter) channel(c float32) uint32 {
+	switch {
+
+	case c <= 0:
+		return f.gamma[0]
+
+	case c >= 1:
+		return f.gamma[GAMMA_STEPS]
+
+	default:
+		return f.gamma[int(c*GAMMA_STEPS)]
+	}
+}
+
+// window is a Hann window width samples wide centred on zero.
+func window(d, width float64) float64 {
+	if math.Abs(d) >= width/2 {
+		return 0
+	}
+	return 0.5 + 0.5*math.Cos(2*math.Pi*d/width)
+}
+
+// windowSum normalises window for centres halfway between samples, which is
+// where every output pixel lands.
+func windowSum(width float64) float64 {
+	var sum float64
+	for d := -math.Ceil(width) - 0.5; d < width; d++ {
+		sum += window(d, width)
+	}
+	return sum
+}
@@ -0,0 +1,87 @@
+package emu
+
+import (
+	"time"
+)
+
+const (
+	// An NTSC frame is 262 lines of 341 dots with one dot skipped on odd frames,
+	// at a PPU clock of 21.477272 MHz / 4.
+	NTSC_FRAMERATE = 60.0988
+	FRAMETIME      = time.Second * 10000 / 600988
+
+	// A wall-clock pacer that falls this far behind gives up catching up and
+	// starts counting from now again.
+	MAX_DRIFT = 5 * FRAMETIME
+)
+
+type Pacer interface {
+	Pace()
+	Drift() time.Duration
+}
+
+// FrameClock counts emulated frames against the monotonic clock, so drift is
+// how far real time has run ahead of (positive) or behind (negative) the
+// emulated time.
+type FrameClock struct {
+	start  time.Time
+	frames int64
+}
+
+func NewFrameClock() FrameClock {
+	return FrameClock{start: time.Now()}
+}
+
+func (c *FrameClock) Tick() {
+	c.frames++
+}
+
+func (c *FrameClock) Target() time.Time {
+	return c.start.Add(time.Duration(float64(c.frames) * float64(time.Second) / NTSC_FRAMERATE))
+}
+
+func (c *FrameClock) Drift() time.Duration {
+	return time.Since(c.Target())
+}
+
+func (c *FrameClock) Reset() {
+	c.start = time.Now()
+	c.frames = 0
+}
+
+type WallClockPacer struct {
+	clock FrameClock
+}
+
+func NewWallClockPacer() *WallClockPacer {
+	return &WallClockPacer{clock: NewFrameClock()}
+}
+
+func (p *WallClockPacer) Pace() {
+	p.clock.Tick()
+	if d := p.clock.Drift(); d < 0 {
+		time.Sleep(-d)
+	} else if d > MAX_DRIFT {
+		p.clock.Reset()
+	}
+}
+
+func (p *WallClockPacer) Drift() time.Duration {
+	return p.clock.Drift()
+}
+
+type UnthrottledPacer struct {
+	clock FrameClock
+}
+
+func NewUnthrottledPacer() *UnthrottledPacer {
+	return &UnthrottledPacer{clock: NewFrameClock()}
+}
+
+func (p *UnthrottledPacer) Pace() {
+	p.clock.Tick()
+}
+
+func (p *UnthrottledPacer) Drift() time.Duration {
+	return p.clock.Drift()
+}
@@ -0,0 +1,185 @@
+package emu
+
+import (
+	"errors"
+	"math"
+
+	"github.com/is386/NESify/emu/bits"
+)
+
+const (
+	PALETTE_COLORS   = 64
+	EMPHASIS         = 0.816328
+	NTSC_BLACK       = 0.518
+	NTSC_WHITE       = 1.962
+	NTSC_ATTENUATION = 0.746
+	NTSC_BURST       = 4
+)
+
+var (
+	ErrPaletteSize = errors.New("palette files must be 192 or 1536 bytes")
+
+	// Signal voltages for each luma level, low half then high half of the
+	// square wave.
+	NTSC_LEVELS = [8]float64{
+		0.350, 0.518, 0.962, 1.550,
+		1.094, 1.506, 1.962, 1.962,
+	}
+
+	DEFAULT_NTSC = NtscSettings{Saturation: 1, Contrast: 1, Gamma: 2.2}
+)
+
+// Palette holds a colour for every palette index under each of the eight
+// PPUMASK emphasis combinations, in the same order as a 1536-byte .pal file.
+type Palette [PALETTE_COLORS * 8]uint32
+
+// NtscSettings tunes the generated palette. Hue is in degrees and Gamma is the
+// display gamma, where 2.2 leaves the decoded colours untouched.
+type NtscSettings struct {
+	Hue, Saturation, Contrast, Gamma float64
+}
+
+func DefaultPalette() *Palette {
+	return withEmphasis(COLORS)
+}
+
+// LoadPalette reads a .pal file. 192-byte files only cover the base colours,
+// so their emphasis variants are derived the same way as the default palette.
+func LoadPalette(data []uint8) (*Palette, error) {
+	switch len(data) {
+
+	case PALETTE_COLORS * 3:
+		colors := make([]uint32, PALETTE_COLORS)
+		for i := range colors {
+			colors[i] = rgb(data[i*3:])
+		}
+		return withEmphasis(colors), nil
+
+	case PALETTE_COLORS * 8 * 3:
+		p := &Palette{}
+		for i := range p {
+			p[i] = rgb(data[i*3:])
+		}
+		return p, nil
+
+	default:
+		return nil, ErrPaletteSize
+	}
+}
+
+// NewNtscPalette decodes every colour from the composite signal the PPU would
+// put out, one colour subcarrier cycle at a time.
+func NewNtscPalette(s NtscSettings) *Palette {
+	p := &Palette{}
+	for pixel := range p {
+		y, i, q := ntscDecode(uint16(pixel), s.Hue)
+		c := yiqToRgb(y*s.Contrast, i*s.Contrast*s.Saturation, q*s.Contrast*s.Saturation)
+		p[pixel] = gammaCorrect(c[0], s.Gamma)<<16 | gammaCorrect(c[1], s.Gamma)<<8 | gammaCorrect(c[2], s.Gamma)
+	}
+	return p
+}
+
+func ntscDecode(pixel uint16, hue float64) (y, i, q float64) {
+	for phase := 0; phase < 12; phase++ {
+		signal := ntscSignal(pixel, phase)
+		angle := ntscAngle(phase, hue)
+		y += signal
+		i += signal * math.Cos(angle)
+		q += signal * math.Sin(angle)
+	}
+	return y / 12, i / 12, q / 12
+}
+
+// NTSC_BURST lines the decoder up with the colour burst so that a hue of 0 is
+// neutral.
+func ntscAngle(phase int, hue float64) float64 {
+	return math.Pi*float64(phase+NTSC_BURST)/6 + hue*math.Pi/180
+}
+
+// ntscSignal returns the normalised composite level for one of the twelve
+// subcarrier phases of a pixel. The pixel is a palette index with the
+// emphasis bits above it, as in the PPU's output.
+func ntscSignal(pixel uint16, phase int) float64 {
+	color := int(pixel & 0x0F)
+	level := int(pixel>>4) & 3
+	emphasis := uint8(pixel >> 6)
+	if color > 13 {
+		level = 1
+	}
+
+	low, high := NTSC_LEVELS[level], NTSC_LEVELS[level+4]
+	if color == 0 {
+		low = high
+	}
+	if color > 12 {
+		high = low
+	}
+
+	inPhase := func(c int) bool {
+		return (c+phase)%12 < 6
+	}
+
+	signal := low
+	if inPhase(color) {
+		signal = high
+	}
+
+	switch {
+
+	case bits.Test(emphasis, 0) && inPhase(0),
+		bits.Test(emphasis, 1) && inPhase(4),
+		bits.Test(emphasis, 2) && inPhase(8):
+		signal *= NTSC_ATTENUATION
+	}
+	return (signal - NTSC_BLACK) / (NTSC_WHITE - NTSC_BLACK)
+}
+
+func yiqToRgb(y, i, q float64) [3]float64 {
+	return [3]float64{
+		y + 0.946882*i + 0.623557*q,
+		y - 0.274788*i - 0.635691*q,
+		y - 1.108545*i + 1.709007*q,
+	}
+}
+
+// gammaCorrect turns a linear channel into a byte for a display with the
+// given gamma.
+func gammaCorrect(c, gamma float64) uint32 {
+	if c > 0 {
+		c = math.Pow(c, 2.2/gamma)
+	}
+	return uint32(math.Round(math.Max(0, math.Min(1, c)) * 255))
+}
+
+func withEmphasis(colors []uint32) *Palette {
+	p := &Palette{}
+	for e := uint8(0); e < 8; e++ {
+		for i, color := range colors {
+			if e != 0 && i&0x0F < 0x0E {
+				color = emphasize(color, e)
+			}
+			p[int(e)*PALETTE_COLORS+i] = color
+		}
+	}
+	return p
+}
+
+func rgb(data []uint8) uint32 {
+	return uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2])
+}
+
+// Emphasising a channel darkens the other two.
+func emphasize(color uint32, e uint8) uint32 {
+	var out uint32
+	for ch := uint8(0); ch < 3; ch++ {
+		shift := 16 - 8*ch
+		c := float64((color >> shift) & 0xFF)
+		for bit := uint8(0); bit < 3; bit++ {
+			if bits.Test(e, bit) && bit != ch {
+				c *= EMPHASIS
+			}
+		}
+		out |= uint32(c) << shift
+	}
+	return out
+}
@@ -4,27 +4,19 @@ import (
 	"github.com/is386/NESify/emu/bits"
 )
 
-// TODO:
-// - Sprite overlap priority
-// - 8x16 sprites
-// - NameTable mirroring
-// - Register Sharing
-
 const (
-	NES_WIDTH  = 256
-	NES_HEIGHT = 240
-	CHR_WIDTH  = 128
-	CHR_HEIGHT = 256
-	SCALE      = 2
-	PPUCTRL    = 0x2000
-	PPUMASK    = 0x2001
-	PPUSTATUS  = 0x2002
-	OAMADDR    = 0x2003
-	OAMDATA    = 0x2004
-	PPUSCROLL  = 0x2005
-	PPUADDR    = 0x2006
-	PPUDATA    = 0x2007
-	OAMDMA     = 0x4014
+	NES_WIDTH   = 256
+	NES_HEIGHT  = 240
+	PPUCTRL     = 0x2000
+	PPUMASK     = 0x2001
+	PPUSTATUS   = 0x2002
+	OAMADDR     = 0x2003
+	OAMDATA     = 0x2004
+	PPUSCROLL   = 0x2005
+	PPUADDR     = 0x2006
+	PPUDATA     = 0x2007
+	OAMDMA      = 0x4014
+	MAX_SPRITES = 8
 )
 
 var (
@@ -41,164 +33,369 @@ var (
 )
 
 type PPU struct {
-	cpu                                              *CPU
-	bus                                              *PpuBus
-	screen                                           *Screen
-	bgPixels                                         [NES_WIDTH][NES_HEIGHT]uint8
-	scanline, cyc, scrollX, scrollY                  int
-	addr                                             uint16
-	ppuCtrl, ppuMask, ppuStatus, oamAddr, dataBuffer uint8
-	nmiOccurred, nmiOutput, isSecondWrite            bool
+	cpu                                  *CPU
+	bus                                  *PpuBus
+	sink                                 VideoSink
+	palette                              *Palette
+	pixels                               [NES_WIDTH * NES_HEIGHT]uint32
+	indices                              [NES_WIDTH * NES_HEIGHT]uint16
+	secondaryOam                         [MAX_SPRITES * 4]uint8
+	spriteLo, spriteHi, spriteX          [MAX_SPRITES]uint8
+	spriteAttr                           [MAX_SPRITES]uint8
+	spriteCount                          int
+	spriteZero                           bool
+	scanline, cyc                        int
+	frame                                uint64
+	v, t                                 uint16
+	x                                    uint8
+	w                                    bool
+	ntByte, atByte, loByte, hiByte       uint8
+	bgShiftLo, bgShiftHi                 uint16
+	atShiftLo, atShiftHi                 uint16
+	ppuCtrl, ppuMask, ppuStatus, oamAddr uint8
+	dataBuffer, latch                    uint8
+	nmiOccurred, nmiOutput               bool
 }
 
 func NewPPU(b *PpuBus) *PPU {
-	p := &PPU{bus: b}
-	p.screen = NewScreen(NES_WIDTH, NES_HEIGHT, SCALE)
-	p.screen.win.SetTitle("NESify")
+	p := &PPU{bus: b, palette: DefaultPalette()}
 	return p
 }
 
+// reset clears the registers that the reset line clears. VRAM, OAM and the
+// position in the frame are left as they are.
+func (p *PPU) reset() {
+	p.ppuCtrl, p.ppuMask = 0, 0
+	p.nmiOutput = false
+	p.t, p.x, p.w = 0, 0, false
+	p.dataBuffer = 0
+}
+
 func (p *PPU) update() {
+	p.bus.clock++
+	p.tick()
+
+	visible := p.scanline <= 239
+	preRender := p.scanline == 261
+	if p.renderingEnabled() && (visible || preRender) {
+		p.renderDot(preRender)
+	}
+
+	if visible && p.cyc >= 1 && p.cyc <= 256 {
+		p.renderPixel()
+	} else if p.scanline == 241 && p.cyc == 1 {
+		p.enterVblank()
+	} else if preRender && p.cyc == 1 {
+		p.exitVblank()
+	}
+}
+
+// The pre-render line is one dot shorter on odd frames while rendering.
+func (p *PPU) tick() {
+	if p.scanline == 261 && p.cyc == 339 && p.frame%2 == 1 && p.renderingEnabled() {
+		p.cyc++
+	}
+
 	p.cyc++
 	if p.cyc > 340 {
 		p.cyc -= 341
 		p.scanline++
+		if p.scanline > 261 {
+			p.endFrame()
+		}
 	}
+}
 
-	if p.scanline >= 0 && p.scanline <= 239 {
-		if p.cyc == 230 {
-			p.renderBackground()
-			p.renderSprites()
+func (p *PPU) renderDot(preRender bool) {
+	if (p.cyc >= 2 && p.cyc <= 257) || (p.cyc >= 321 && p.cyc <= 337) {
+		p.shiftBackground()
+		switch (p.cyc - 1) % 8 {
+
+		case 0:
+			p.loadBackground()
+			p.fetchNameTable()
+
+		case 2:
+			p.fetchAttribute()
+
+		case 4:
+			p.loByte = p.bus.read(p.tileAddr())
+
+		case 6:
+			p.hiByte = p.bus.read(p.tileAddr() + 8)
+
+		case 7:
+			p.incrementX()
 		}
-	} else if p.scanline == 241 && p.cyc == 1 {
-		p.enterVblank()
-	} else if p.scanline == 261 && p.cyc == 1 {
-		p.exitVblank()
+	}
+
+	switch {
+
+	case p.cyc == 256:
+		p.incrementY()
+
+	case p.cyc == 257:
+		p.v = (p.v & 0xFBE0) | (p.t & 0x041F)
+		p.evaluateSprites(!preRender)
+
+	case p.cyc == 338 || p.cyc == 340:
+		p.fetchNameTable()
+
+	case preRender && p.cyc >= 280 && p.cyc <= 304:
+		p.v = (p.v & 0x841F) | (p.t & 0x7BE0)
+	}
+
+	if p.cyc >= 257 && p.cyc <= 320 {
+		p.oamAddr = 0
+		p.fetchSprite((p.cyc-257)/8, (p.cyc-257)%8)
 	}
 }
 
-func (p *PPU) renderBackground() {
-	for x := 0; x < NES_WIDTH; x++ {
-		scrolledX := (x + p.scrollX) % 256
-		scrolledY := (p.scanline + p.scrollY) % 240
+func (p *PPU) renderPixel() {
+	x := p.cyc - 1
+	if !p.renderingEnabled() {
+		addr := uint16(0x3F00)
+		if p.v >= 0x3F00 {
+			addr = p.v
+		}
+		p.setPixel(x, p.scanline, p.paletteColor(addr))
+		return
+	}
+
+	var pixel, palette uint8
+	if p.showBackground(x) {
+		bit := 15 - uint16(p.x)
+		pixel = uint8((p.bgShiftHi>>bit)&1)<<1 | uint8((p.bgShiftLo>>bit)&1)
+		palette = uint8((p.atShiftHi>>bit)&1)<<1 | uint8((p.atShiftLo>>bit)&1)
+	}
+
+	var spritePixel, spritePalette uint8
+	var behind, zero bool
+	if p.showSprites(x) {
+		spritePixel, spritePalette, behind, zero = p.spritePixel(x)
+	}
+
+	switch {
+
+	case spritePixel == 0:
+		p.setPixel(x, p.scanline, p.getColor(palette, pixel))
 
-		ntX := (x + p.scrollX) / 8
-		ntY := (p.scanline + p.scrollY) / 8
-		ntBaseAddr := p.getNameTableAddr()
-		ntAddr := uint16((ntY*32)+ntX) + ntBaseAddr
+	case pixel == 0:
+		p.setPixel(x, p.scanline, p.getColor(spritePalette, spritePixel))
+
+	default:
+		if zero && x != 255 {
+			p.setZeroHit()
+		}
+		if behind {
+			p.setPixel(x, p.scanline, p.getColor(palette, pixel))
+		} else {
+			p.setPixel(x, p.scanline, p.getColor(spritePalette, spritePixel))
+		}
+	}
+}
 
-		if ntY > 29 {
-			ntAddr = ((ntAddr + 0x40) ^ 0x800) ^ 0x400
-			ntBaseAddr ^= 0x800
+// The first opaque sprite in OAM order wins, even when it sits behind the
+// background and a later sprite would otherwise show through.
+func (p *PPU) spritePixel(x int) (pixel, palette uint8, behind, zero bool) {
+	for i := 0; i < p.spriteCount; i++ {
+		offset := x - int(p.spriteX[i])
+		if offset < 0 || offset > 7 {
+			continue
 		}
-		if ntX > 31 {
-			ntAddr = (ntAddr ^ 0x400) - 0x20
-			ntBaseAddr ^= 0x400
+
+		bit := uint8(7 - offset)
+		pixel = (p.spriteHi[i]>>bit)&1<<1 | (p.spriteLo[i]>>bit)&1
+		if pixel == 0 {
+			continue
 		}
 
-		ptIdx := p.bus.read(ntAddr)
-		ptAddr := p.getBgPatternTableAddr() + (uint16(ptIdx) * 16) + uint16(scrolledY%8)
-		ptByte1 := p.bus.read(ptAddr)
-		ptByte2 := p.bus.read(ptAddr + 8)
+		attrs := p.spriteAttr[i]
+		return pixel, (attrs & 3) + 4, bits.Test(attrs, 5), i == 0 && p.spriteZero
+	}
+	return 0, 0, false, false
+}
+
+func (p *PPU) fetchNameTable() {
+	p.ntByte = p.bus.read(0x2000 | (p.v & 0x0FFF))
+}
+
+func (p *PPU) fetchAttribute() {
+	addr := 0x23C0 | (p.v & 0x0C00) | ((p.v >> 4) & 0x38) | ((p.v >> 2) & 0x07)
+	shift := ((p.v >> 4) & 4) | (p.v & 2)
+	p.atByte = (p.bus.read(addr) >> shift) & 3
+}
 
-		pixel := 7 - (scrolledX % 8)
-		colorBit0 := (ptByte1 >> pixel) & 1
-		colorBit1 := (ptByte2 >> pixel) & 1
-		colorNum := (colorBit1 << 1) | colorBit0
-		p.bgPixels[x][p.scanline] = colorNum
+func (p *PPU) tileAddr() uint16 {
+	return p.getBgPatternTableAddr() + uint16(p.ntByte)*16 + (p.v>>12)&7
+}
 
-		blockX := scrolledX / 32
-		blockY := scrolledY / 32
-		blockAddr := uint16(8*blockY) + uint16(blockX) + ntBaseAddr + 0x3C0
-		blockByte := p.bus.read(blockAddr)
+func (p *PPU) shiftBackground() {
+	p.bgShiftLo <<= 1
+	p.bgShiftHi <<= 1
+	p.atShiftLo <<= 1
+	p.atShiftHi <<= 1
+}
 
-		quadX := scrolledX / 16
-		quadY := scrolledY / 16
-		quad := uint8(((quadY % 2) << 1) | (quadX % 2))
+func (p *PPU) loadBackground() {
+	p.bgShiftLo = (p.bgShiftLo & 0xFF00) | uint16(p.loByte)
+	p.bgShiftHi = (p.bgShiftHi & 0xFF00) | uint16(p.hiByte)
+	p.atShiftLo = (p.atShiftLo & 0xFF00) | 0xFF*uint16(p.atByte&1)
+	p.atShiftHi = (p.atShiftHi & 0xFF00) | 0xFF*uint16(p.atByte>>1)
+}
 
-		paletteBit0 := bits.Value(blockByte, (quad * 2))
-		paletteBit1 := bits.Value(blockByte, (quad*2)+1)
-		paletteNum := (paletteBit1 << 1) | paletteBit0
-		color := p.getPalette(int(paletteNum))[colorNum]
-		p.screen.drawPixel(int32(x), int32(p.scanline), color)
+func (p *PPU) incrementX() {
+	if (p.v & 0x001F) == 31 {
+		p.v &^= 0x001F
+		p.v ^= 0x0400
+	} else {
+		p.v++
 	}
 }
 
-func (p *PPU) renderSprites() {
-	for oamAddr := 0; oamAddr < 256; oamAddr += 4 {
-		spriteHeight := 8
-		spriteY := int(p.bus.readOam(uint8(oamAddr)))
-		spriteX := int(p.bus.readOam(uint8(oamAddr) + 3))
+func (p *PPU) incrementY() {
+	if (p.v & 0x7000) != 0x7000 {
+		p.v += 0x1000
+		return
+	}
+
+	p.v &^= 0x7000
+	y := (p.v & 0x03E0) >> 5
+	switch y {
+
+	case 29:
+		y = 0
+		p.v ^= 0x0800
+
+	case 31:
+		y = 0
 
-		if p.scanline < spriteY || p.scanline >= (spriteY+spriteHeight) {
+	default:
+		y++
+	}
+	p.v = (p.v &^ 0x03E0) | (y << 5)
+}
+
+// Secondary OAM is filled in one go at the end of the line, but the scan
+// follows the hardware, including the diagonal OAM walk that makes the
+// overflow flag unreliable once eight sprites have been found.
+func (p *PPU) evaluateSprites(visible bool) {
+	for i := range p.secondaryOam {
+		p.secondaryOam[i] = 0xFF
+	}
+	p.spriteCount = 0
+	p.spriteZero = false
+	if !visible {
+		return
+	}
+
+	n := 0
+	for ; n < 64 && p.spriteCount < MAX_SPRITES; n++ {
+		y := p.bus.readOam(uint8(n * 4))
+		if !p.spriteOnLine(y) {
 			continue
 		}
 
-		if oamAddr == 0 && spriteX < 255 {
-			p.setZeroHit()
+		for i := 0; i < 4; i++ {
+			p.secondaryOam[p.spriteCount*4+i] = p.bus.readOam(uint8(n*4 + i))
 		}
-
-		attrs := p.bus.readOam(uint8(oamAddr) + 2)
-		paletteNum := (attrs & 3) + 4
-		bgPriority := bits.Test(attrs, 5)
-		xFlip := bits.Test(attrs, 6)
-		yFlip := bits.Test(attrs, 7)
-
-		y := p.scanline - spriteY
-		if yFlip {
-			y = spriteHeight - y - 1
+		if n == 0 {
+			p.spriteZero = true
 		}
+		p.spriteCount++
+	}
 
-		tileIdx := p.bus.readOam(uint8(oamAddr) + 1)
-		ptAddr := p.getSpritePatternTableAddr() + (uint16(tileIdx) * 16) + uint16(y%8)
-		ptByte1 := p.bus.read(ptAddr)
-		ptByte2 := p.bus.read(ptAddr + 8)
-
-		diff := (8 - (spriteX % 8))
-		for x := spriteX; x < spriteX+8; x++ {
-			if x >= NES_WIDTH {
-				break
-			}
-
-			pixel := 7 - ((x + diff) % 8)
-			if xFlip {
-				pixel = 7 - pixel
-			}
-			colorBit0 := (ptByte1 >> pixel) & 1
-			colorBit1 := (ptByte2 >> pixel) & 1
-			colorNum := (colorBit1 << 1) | colorBit0
-
-			if colorNum == 0 || (bgPriority && p.bgPixels[x][p.scanline] != 0) {
-				continue
-			}
-
-			color := p.getPalette(int(paletteNum))[colorNum]
-			p.screen.drawPixel(int32(x), int32(p.scanline), color)
+	m := 0
+	for ; n < 64; n++ {
+		if p.spriteOnLine(p.bus.readOam(uint8(n*4 + m))) {
+			p.setOverflow()
+			break
 		}
+		m = (m + 1) & 3
 	}
 }
 
-func (p *PPU) showCHR() {
-	for y := 0; y < CHR_HEIGHT; y++ {
-		for x := 0; x < CHR_WIDTH; x++ {
-			addr := uint16((y / 8 * 0x100) + (y % 8) + (x/8)*0x10)
-			tileByte1 := p.bus.read(addr)
-			tileByte2 := p.bus.read(addr + 8)
-			pixel := 7 - (x % 8)
-			colorBit0 := (tileByte1 >> pixel) & 1
-			colorBit1 := (tileByte2 >> pixel) & 1
-			colorNum := (colorBit1 << 1) | colorBit0
-			p.screen.drawPixel(int32(x)+NES_WIDTH, int32(y), COLORS[colorNum*3])
-		}
+func (p *PPU) spriteOnLine(y uint8) bool {
+	row := p.scanline - int(y)
+	return row >= 0 && row < p.spriteHeight()
+}
+
+// Each of the eight slots takes eight dots. Empty slots still fetch tile $FF
+// so mappers watching A12 see the same pattern as on hardware.
+func (p *PPU) fetchSprite(slot, dot int) {
+	if dot != 4 && dot != 6 {
+		return
+	}
+
+	sprite := p.secondaryOam[slot*4 : slot*4+4]
+	addr := p.spriteAddr(sprite[0], sprite[1], sprite[2])
+	if dot == 6 {
+		addr += 8
+	}
+	data := p.bus.read(addr)
+
+	switch {
+
+	case slot >= p.spriteCount:
+		data = 0
+
+	case bits.Test(sprite[2], 6):
+		data = reverseBits(data)
+	}
+
+	if dot == 4 {
+		p.spriteLo[slot] = data
+	} else {
+		p.spriteHi[slot] = data
+		p.spriteAttr[slot] = sprite[2]
+		p.spriteX[slot] = sprite[3]
+	}
+}
+
+func (p *PPU) spriteAddr(y, tile, attrs uint8) uint16 {
+	row := uint16(p.scanline-int(y)) & 0x0F
+	if bits.Test(attrs, 7) {
+		row = uint16(p.spriteHeight()-1) - row
+	}
+
+	if p.spriteHeight() == 8 {
+		return p.getSpritePatternTableAddr() + uint16(tile)*16 + row&7
+	}
+
+	addr := 0x1000*uint16(tile&1) + uint16(tile&0xFE)*16
+	if row >= 8 {
+		addr += 16
+	}
+	return addr + row&7
+}
+
+func (p *PPU) spriteHeight() int {
+	if bits.Test(p.ppuCtrl, 5) {
+		return 16
 	}
-	p.screen.update()
+	return 8
+}
+
+func reverseBits(b uint8) uint8 {
+	b = (b&0xF0)>>4 | (b&0x0F)<<4
+	b = (b&0xCC)>>2 | (b&0x33)<<2
+	return (b&0xAA)>>1 | (b&0x55)<<1
+}
+
+func (p *PPU) setPixel(x, y int, color uint16) {
+	p.indices[y*NES_WIDTH+x] = color
+	p.pixels[y*NES_WIDTH+x] = p.palette[color]
 }
 
 func (p *PPU) readRegister(addr uint16) uint8 {
 	switch addr {
 
 	case PPUSTATUS:
-		return p.ppuStatus
+		data := (p.ppuStatus & 0xE0) | (p.latch & 0x1F)
+		p.ppuStatus = bits.Reset(p.ppuStatus, 7)
+		p.nmiOccurred = false
+		p.w = false
+		return data
 
 	case OAMDATA:
 		data := p.bus.readOam(p.oamAddr)
@@ -208,31 +405,36 @@ func (p *PPU) readRegister(addr uint16) uint8 {
 		return data
 
 	case PPUDATA:
-		data := p.bus.read(p.addr)
-		if p.addr < 0x3F00 {
+		data := p.bus.read(p.v)
+		if (p.v & 0x3FFF) < 0x3F00 {
 			data, p.dataBuffer = p.dataBuffer, data
+		} else {
+			p.dataBuffer = p.bus.read(p.v - 0x1000)
 		}
-		p.addr += p.getAddrIncrement()
+		p.incrementAddr()
 		return data
 
 	default:
-		return 0
+		return p.latch
 	}
 }
 
 func (p *PPU) writeRegister(addr uint16, val uint8) {
+	p.latch = val
 	switch addr {
 
 	case PPUCTRL:
+		nmiOutput := p.nmiOutput
 		p.nmiOutput = bits.Test(val, 7)
 		p.ppuCtrl = val
+		p.t = (p.t & 0xF3FF) | (uint16(val&3) << 10)
+		if !nmiOutput && p.nmiOutput && p.nmiOccurred {
+			p.cpu.triggerInterrupt(Nmi)
+		}
 
 	case PPUMASK:
 		p.ppuMask = val
 
-	case PPUSTATUS:
-		p.ppuStatus = val
-
 	case OAMADDR:
 		p.oamAddr = val
 
@@ -241,37 +443,38 @@ func (p *PPU) writeRegister(addr uint16, val uint8) {
 		p.oamAddr++
 
 	case PPUSCROLL:
-		if p.isSecondWrite {
-			p.scrollY = int(val)
+		if p.w {
+			p.t = (p.t & 0x8C1F) | (uint16(val&0x07) << 12) | (uint16(val&0xF8) << 2)
 		} else {
-			p.scrollX = int(val)
+			p.t = (p.t & 0xFFE0) | uint16(val>>3)
+			p.x = val & 7
 		}
-		p.isSecondWrite = !p.isSecondWrite
+		p.w = !p.w
 
 	case PPUADDR:
-		if p.isSecondWrite {
-			p.addr = (p.addr << 8) | uint16(val)
+		if p.w {
+			p.t = (p.t & 0xFF00) | uint16(val)
+			p.v = p.t
 		} else {
-			p.addr = uint16(val)
+			p.t = (p.t & 0x80FF) | (uint16(val&0x3F) << 8)
 		}
-		p.isSecondWrite = !p.isSecondWrite
+		p.w = !p.w
 
 	case PPUDATA:
-		p.bus.write(p.addr, val)
-		p.addr += p.getAddrIncrement()
-
-	case OAMDMA:
-		cpuAddr := uint16(val) << 8
-		for i := uint16(0); i < 256; i++ {
-			p.bus.writeOam(p.oamAddr, p.cpu.read(cpuAddr+i))
-			p.oamAddr++
-		}
-		p.cpu.stallForDma()
+		p.bus.write(p.v, val)
+		p.incrementAddr()
 	}
 }
 
-func (p *PPU) getNameTableAddr() uint16 {
-	return 0x2000 + (0x400 * uint16(p.ppuCtrl&3))
+// Accessing PPUDATA while rendering bumps both scroll counters instead of
+// adding the usual increment.
+func (p *PPU) incrementAddr() {
+	if p.renderingEnabled() && (p.scanline <= 239 || p.scanline == 261) {
+		p.incrementX()
+		p.incrementY()
+		return
+	}
+	p.v = (p.v + p.getAddrIncrement()) & 0x7FFF
 }
 
 func (p *PPU) getBgPatternTableAddr() uint16 {
@@ -282,6 +485,18 @@ func (p *PPU) getSpritePatternTableAddr() uint16 {
 	return 0x1000 * uint16(bits.Value(p.ppuCtrl, 3))
 }
 
+func (p *PPU) showBackground(x int) bool {
+	return bits.Test(p.ppuMask, 3) && (x >= 8 || bits.Test(p.ppuMask, 1))
+}
+
+func (p *PPU) showSprites(x int) bool {
+	return bits.Test(p.ppuMask, 4) && (x >= 8 || bits.Test(p.ppuMask, 2))
+}
+
+func (p *PPU) renderingEnabled() bool {
+	return (p.ppuMask & 0x18) != 0
+}
+
 func (p *PPU) getAddrIncrement() uint16 {
 	return uint16(bits.Value(p.ppuCtrl, 2)*31) + 1
 }
@@ -297,26 +512,109 @@ func (p *PPU) enterVblank() {
 func (p *PPU) exitVblank() {
 	p.ppuStatus = bits.Reset(p.ppuStatus, 7)
 	p.resetZeroHit()
-	p.bgPixels = [NES_WIDTH][NES_HEIGHT]uint8{}
-	p.scanline = 0
+	p.ppuStatus = bits.Reset(p.ppuStatus, 5)
 	p.nmiOccurred = false
-	p.screen.update()
 }
 
-func (p *PPU) getPalette(num int) [4]uint32 {
-	palette := [4]uint32{COLORS[p.bus.read(uint16(0x3F00))]}
-	addr := 0x3F00 + (num * 4)
-	for i := addr + 1; i < addr+4; i++ {
-		paletteByte := p.bus.read(uint16(i))
-		palette[i-addr] = COLORS[paletteByte]
+func (p *PPU) endFrame() {
+	p.scanline = 0
+	p.frame++
+	if p.sink != nil {
+		p.sink.DrawFrame(p.pixels[:])
+	}
+}
+
+func (p *PPU) getColor(palette, pixel uint8) uint16 {
+	addr := uint16(0x3F00)
+	if pixel != 0 {
+		addr |= uint16(palette)<<2 | uint16(pixel)
 	}
-	return palette
+	return p.paletteColor(addr)
+}
+
+// Colours are palette indices with the PPUMASK emphasis bits above them.
+func (p *PPU) paletteColor(addr uint16) uint16 {
+	index := p.bus.peek(addr) & 0x3F
+	if bits.Test(p.ppuMask, 0) {
+		index &= 0x30
+	}
+	return uint16(p.ppuMask>>5)<<6 | uint16(index)
 }
 
 func (p *PPU) setZeroHit() {
 	p.ppuStatus = bits.Set(p.ppuStatus, 6)
 }
 
+func (p *PPU) setOverflow() {
+	p.ppuStatus = bits.Set(p.ppuStatus, 5)
+}
+
 func (p *PPU) resetZeroHit() {
 	p.ppuStatus = bits.Reset(p.ppuStatus, 6)
 }
+
+func (p *PPU) save(w *stateWriter) {
+	w.bytes(p.secondaryOam[:])
+	w.bytes(p.spriteLo[:])
+	w.bytes(p.spriteHi[:])
+	w.bytes(p.spriteX[:])
+	w.bytes(p.spriteAttr[:])
+	w.i64(p.spriteCount)
+	w.bool(p.spriteZero)
+	w.i64(p.scanline)
+	w.i64(p.cyc)
+	w.u64(p.frame)
+	w.u16(p.v)
+	w.u16(p.t)
+	w.u8(p.x)
+	w.bool(p.w)
+	w.u8(p.ntByte)
+	w.u8(p.atByte)
+	w.u8(p.loByte)
+	w.u8(p.hiByte)
+	w.u16(p.bgShiftLo)
+	w.u16(p.bgShiftHi)
+	w.u16(p.atShiftLo)
+	w.u16(p.atShiftHi)
+	w.u8(p.ppuCtrl)
+	w.u8(p.ppuMask)
+	w.u8(p.ppuStatus)
+	w.u8(p.oamAddr)
+	w.u8(p.dataBuffer)
+	w.u8(p.latch)
+	w.bool(p.nmiOccurred)
+	w.bool(p.nmiOutput)
+}
+
+func (p *PPU) load(r *stateReader) {
+	r.bytes(p.secondaryOam[:])
+	r.bytes(p.spriteLo[:])
+	r.bytes(p.spriteHi[:])
+	r.bytes(p.spriteX[:])
+	r.bytes(p.spriteAttr[:])
+	p.spriteCount = r.i64()
+	p.spriteZero = r.bool()
+	p.scanline = r.i64()
+	p.cyc = r.i64()
+	p.frame = r.u64()
+	p.v = r.u16()
+	p.t = r.u16()
+	p.x = r.u8()
+	p.w = r.bool()
+	p.ntByte = r.u8()
+	p.atByte = r.u8()
+	p.loByte = r.u8()
+	p.hiByte = r.u8()
+	p.bgShiftLo = r.u16()
+	p.bgShiftHi = r.u16()
+	p.atShiftLo = r.u16()
+	p.atShiftHi = r.u16()
+	p.ppuCtrl = r.u8()
+	p.ppuMask = r.u8()
+	p.ppuStatus = r.u8()
+	p.oamAddr = r.u8()
+	p.dataBuffer = r.u8()
+	p.latch = r.u8()
+	p.nmiOccurred = r.bool()
+	p.nmiOutput = r.bool()
+}
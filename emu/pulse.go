@@ -0,0 +1,144 @@
+package emu
+
+import "github.com/is386/NESify/emu/bits"
+
+var (
+	DUTY_TABLE = [4][8]uint8{
+		{0, 1, 0, 0, 0, 0, 0, 0},
+		{0, 1, 1, 0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 1, 0, 0, 0},
+		{1, 0, 0, 1, 1, 1, 1, 1},
+	}
+)
+
+type Pulse struct {
+	channel                   uint8
+	enabled, lengthHalt       bool
+	duty, dutyPos, length     uint8
+	timer, timerPeriod        uint16
+	envelope                  Envelope
+	sweepEnabled, sweepNegate bool
+	sweepReload               bool
+	sweepPeriod, sweepShift   uint8
+	sweepDivider              uint8
+}
+
+func NewPulse(channel uint8) *Pulse {
+	return &Pulse{channel: channel}
+}
+
+func (p *Pulse) writeRegister(reg uint16, val uint8) {
+	switch reg {
+	case 0:
+		p.duty = val >> 6
+		p.lengthHalt = bits.Test(val, 5)
+		p.envelope.write(val)
+	case 1:
+		p.sweepEnabled = bits.Test(val, 7)
+		p.sweepPeriod = (val >> 4) & 7
+		p.sweepNegate = bits.Test(val, 3)
+		p.sweepShift = val & 7
+		p.sweepReload = true
+	case 2:
+		p.timerPeriod = (p.timerPeriod & 0x700) | uint16(val)
+	case 3:
+		p.timerPeriod = (p.timerPeriod & 0xFF) | (uint16(val&7) << 8)
+		if p.enabled {
+			p.length = LENGTH_TABLE[val>>3]
+		}
+		p.dutyPos = 0
+		p.envelope.start = true
+	}
+}
+
+func (p *Pulse) setEnabled(enabled bool) {
+	p.enabled = enabled
+	if !enabled {
+		p.length = 0
+	}
+}
+
+func (p *Pulse) clockTimer() {
+	if p.timer == 0 {
+		p.timer = p.timerPeriod
+		p.dutyPos = (p.dutyPos + 1) % 8
+	} else {
+		p.timer--
+	}
+}
+
+func (p *Pulse) clockLength() {
+	if p.length > 0 && !p.lengthHalt {
+		p.length--
+	}
+}
+
+// Pulse 1 negates with ones' complement and pulse 2 with two's complement, so
+// the two channels sweep down by slightly different amounts.
+func (p *Pulse) targetPeriod() uint16 {
+	change := p.timerPeriod >> p.sweepShift
+	if !p.sweepNegate {
+		return p.timerPeriod + change
+	}
+	if p.channel == 1 {
+		return p.timerPeriod - change - 1
+	}
+	return p.timerPeriod - change
+}
+
+func (p *Pulse) muted() bool {
+	return p.timerPeriod < 8 || p.targetPeriod() > 0x7FF
+}
+
+func (p *Pulse) clockSweep() {
+	if p.sweepDivider == 0 && p.sweepEnabled && p.sweepShift > 0 && !p.muted() {
+		p.timerPeriod = p.targetPeriod()
+	}
+	if p.sweepDivider == 0 || p.sweepReload {
+		p.sweepDivider = p.sweepPeriod
+		p.sweepReload = false
+	} else {
+		p.sweepDivider--
+	}
+}
+
+func (p *Pulse) output() uint8 {
+	if p.length == 0 || p.muted() || DUTY_TABLE[p.duty][p.dutyPos] == 0 {
+		return 0
+	}
+	return p.envelope.output()
+}
+
+func (p *Pulse) save(w *stateWriter) {
+	w.bool(p.enabled)
+	w.bool(p.lengthHalt)
+	w.u8(p.duty)
+	w.u8(p.dutyPos)
+	w.u8(p.length)
+	w.u16(p.timer)
+	w.u16(p.timerPeriod)
+	p.envelope.save(w)
+	w.bool(p.sweepEnabled)
+	w.bool(p.sweepNegate)
+	w.bool(p.sweepReload)
+	w.u8(p.sweepPeriod)
+	w.u8(p.sweepShift)
+	w.u8(p.sweepDivider)
+}
+
+func (p *Pulse) load(r *stateReader) {
+	p.enabled = r.bool()
+	p.lengthHalt = r.bool()
+	p.duty = r.u8()
+	p.dutyPos = r.u8()
+	p.length = r.u8()
+	p.timer = r.u16()
+	p.timerPeriod = r.u16()
+	p.envelope.load(r)
+	p.sweepEnabled = r.bool()
+	p.sweepNegate = r.bool()
+	p.sweepReload = r.bool()
+	p.sweepPeriod = r.u8()
+	p.sweepShift = r.u8()
+	p.sweepDivider = r.u8()
+}
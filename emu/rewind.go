@@ -0,0 +1,141 @@
+package emu
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+)
+
+const (
+	KEYFRAME_INTERVAL = 60
+)
+
+type rewindEntry struct {
+	data     []uint8
+	keyframe bool
+}
+
+// Rewind keeps a history of per-frame save states. Every KEYFRAME_INTERVAL
+// frames a full state is stored, and the frames between hold the XOR of their
+// state against that keyframe, which is almost all zeros and compresses to a
+// few hundred bytes. The oldest keyframe and its deltas are dropped together
+// once the compressed history no longer fits in the budget.
+type Rewind struct {
+	nes                *NES
+	budget, maxEntries int
+	entries            []rewindEntry
+	used               int
+	keyframe           []uint8
+	keyIndex           int
+	state, delta       []uint8
+	buf                bytes.Buffer
+	zw                 *flate.Writer
+}
+
+func NewRewind(nes *NES, budget, maxEntries int) *Rewind {
+	zw, _ := flate.NewWriter(nil, flate.BestSpeed)
+	return &Rewind{nes: nes, budget: budget, maxEntries: maxEntries, keyIndex: -1, zw: zw}
+}
+
+// Push records the machine's current state. It should be called once per
+// frame while the game is running forwards.
+func (r *Rewind) Push() {
+	r.state = r.nes.appendState(r.state[:0])
+
+	last := len(r.entries) - 1
+	isKey := r.keyIndex < 0 || last-r.keyIndex+1 >= KEYFRAME_INTERVAL || len(r.state) != len(r.keyframe)
+	if isKey {
+		r.keyframe = append(r.keyframe[:0], r.state...)
+		r.keyIndex = len(r.entries)
+		r.append(r.state, true)
+	} else {
+		r.delta = r.delta[:0]
+		for i, b := range r.state {
+			r.delta = append(r.delta, b^r.keyframe[i])
+		}
+		r.append(r.delta, false)
+	}
+	r.evict()
+}
+
+// Pop restores the most recent recorded state and removes it from the
+// history. It returns false once there is nothing left to rewind to.
+func (r *Rewind) Pop() bool {
+	if len(r.entries) == 0 {
+		return false
+	}
+
+	last := len(r.entries) - 1
+	e := r.entries[last]
+	if r.keyIndex < 0 || r.keyIndex > last {
+		r.loadKeyframe(last)
+	}
+	state := r.decompress(e.data)
+	if !e.keyframe {
+		for i := range state {
+			state[i] ^= r.keyframe[i]
+		}
+	}
+
+	r.entries = r.entries[:last]
+	r.used -= len(e.data)
+	if e.keyframe {
+		r.keyIndex = -1
+	}
+	return r.nes.LoadState(state) == nil
+}
+
+func (r *Rewind) Len() int {
+	return len(r.entries)
+}
+
+func (r *Rewind) Clear() {
+	r.entries = nil
+	r.used = 0
+	r.keyIndex = -1
+}
+
+func (r *Rewind) append(state []uint8, keyframe bool) {
+	r.buf.Reset()
+	r.zw.Reset(&r.buf)
+	r.zw.Write(state)
+	r.zw.Close()
+
+	data := append([]uint8{}, r.buf.Bytes()...)
+	r.entries = append(r.entries, rewindEntry{data: data, keyframe: keyframe})
+	r.used += len(data)
+}
+
+func (r *Rewind) evict() {
+	for len(r.entries) > 1 && (r.used > r.budget || len(r.entries) > r.maxEntries) {
+		n := 1
+		for n < len(r.entries) && !r.entries[n].keyframe {
+			n++
+		}
+		if n == len(r.entries) {
+			return
+		}
+		for _, e := range r.entries[:n] {
+			r.used -= len(e.data)
+		}
+		r.entries = append(r.entries[:0], r.entries[n:]...)
+		r.keyIndex -= n
+	}
+}
+
+func (r *Rewind) loadKeyframe(from int) {
+	for i := from; i >= 0; i-- {
+		if r.entries[i].keyframe {
+			r.keyframe = r.decompress(r.entries[i].data)
+			r.keyIndex = i
+			return
+		}
+	}
+}
+
+func (r *Rewind) decompress(data []uint8) []uint8 {
+	zr := flate.NewReader(bytes.NewReader(data))
+	defer zr.Close()
+	state, _ := ioutil.ReadAll(zr)
+	return state
+}
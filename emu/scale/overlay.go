@@ -0,0 +1,41 @@
+package scale
+
+const (
+	SCANLINE_LEVEL = 50
+	MASK_LEVEL     = 70
+)
+
+// Scanlines darkens the bottom row of every source line, as if the beam left
+// a gap between them. Lines only one row tall are left alone.
+func Scanlines(dst, src []uint32, width, height, lineHeight int) {
+	copy(dst, src[:width*height])
+	if lineHeight < 2 {
+		return
+	}
+	for y := lineHeight - 1; y < height; y += lineHeight {
+		row := dst[y*width : (y+1)*width]
+		for x, c := range row {
+			row[x] = dim(c, SCANLINE_LEVEL)
+		}
+	}
+}
+
+// CrtMask imitates an aperture grille: columns cycle through red, green and
+// blue phosphors, each dimming the other two channels.
+func CrtMask(dst, src []uint32, width, height, lineHeight int) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := src[y*width+x]
+			keep := uint32(0xFF) << (16 - 8*uint(x%3))
+			dst[y*width+x] = c&keep | dim(c&^keep, MASK_LEVEL)
+		}
+	}
+}
+
+// dim scales every channel of c to level percent.
+func dim(c uint32, level uint32) uint32 {
+	r := (c >> 16 & 0xFF) * level / 100
+	g := (c >> 8 & 0xFF) * level / 100
+	b := (c & 0xFF) * level / 100
+	return r<<16 | g<<8 | b
+}
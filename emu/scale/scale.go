@@ -0,0 +1,101 @@
+package scale
+
+// A Func scales a width*height frame in src into dst, which must hold the
+// frame at the scaler's factor. It reads nothing but src and writes nothing
+// but dst.
+type Func func(dst, src []uint32, width, height int)
+
+// An OverlayFunc draws over a width*height frame in which every source line is
+// lineHeight rows tall. dst and src are the same size.
+type OverlayFunc func(dst, src []uint32, width, height, lineHeight int)
+
+type Scaler struct {
+	Name   string
+	Factor int
+	Apply  Func
+}
+
+type Overlay struct {
+	Name  string
+	Apply OverlayFunc
+}
+
+var (
+	SCALERS = []Scaler{
+		{"none", 1, Copy},
+		{"scale2x", 2, Scale2x},
+		{"scale3x", 3, Scale3x},
+		{"xbr", 2, Xbr},
+	}
+
+	OVERLAYS = []Overlay{
+		{"none", func(dst, src []uint32, width, height, lineHeight int) { copy(dst, src) }},
+		{"scanlines", Scanlines},
+		{"mask", CrtMask},
+	}
+)
+
+func Copy(dst, src []uint32, width, height int) {
+	copy(dst, src[:width*height])
+}
+
+// at reads src with coordinates clamped to the frame, so the edges repeat.
+func at(src []uint32, width, height, x, y int) uint32 {
+	return src[index(width, height, x, y)]
+}
+
+func index(width, height, x, y int) int {
+	switch {
+
+	case x < 0:
+		x = 0
+
+	case x >= width:
+		x = width - 1
+	}
+
+	switch {
+
+	case y < 0:
+		y = 0
+
+	case y >= height:
+		y = height - 1
+	}
+	return y*width + x
+}
+
+// mix blends three colours channel by channel with the given weights. Pass a
+// weight of 0 to blend fewer.
+func mix(a, b, c uint32, wa, wb, wc uint32) uint32 {
+	total := wa + wb + wc
+	red := ((a>>16&0xFF)*wa + (b>>16&0xFF)*wb + (c>>16&0xFF)*wc) / total
+	green := ((a>>8&0xFF)*wa + (b>>8&0xFF)*wb + (c>>8&0xFF)*wc) / total
+	blue := ((a&0xFF)*wa + (b&0xFF)*wb + (c&0xFF)*wc) / total
+	return red<<16 | green<<8 | blue
+}
+
+// yuv holds a colour's luma and two chroma components, scaled by 1000.
+type yuv struct {
+	y, u, v int
+}
+
+func toYuv(src []uint32) []yuv {
+	out := make([]yuv, len(src))
+	for i, c := range src {
+		r, g, b := int(c>>16&0xFF), int(c>>8&0xFF), int(c&0xFF)
+		out[i] = yuv{
+			y: 299*r + 587*g + 114*b,
+			u: -169*r - 331*g + 500*b,
+			v: 500*r - 419*g - 81*b,
+		}
+	}
+	return out
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
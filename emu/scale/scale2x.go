@@ -0,0 +1,86 @@
+package scale
+
+// Scale2x is Andrea Mazzoleni's EPX variant. Each pixel becomes a 2x2 block
+// whose corners take a neighbour's colour where two neighbours meet at that
+// corner and the opposite ones do not.
+func Scale2x(dst, src []uint32, width, height int) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := src[y*width+x]
+			a := at(src, width, height, x, y-1)
+			b := at(src, width, height, x+1, y)
+			c := at(src, width, height, x-1, y)
+			d := at(src, width, height, x, y+1)
+
+			e0, e1, e2, e3 := p, p, p, p
+			if a != d && c != b {
+				if c == a {
+					e0 = a
+				}
+				if a == b {
+					e1 = b
+				}
+				if c == d {
+					e2 = c
+				}
+				if d == b {
+					e3 = d
+				}
+			}
+
+			i := y*2*width*2 + x*2
+			dst[i], dst[i+1] = e0, e1
+			dst[i+width*2], dst[i+width*2+1] = e2, e3
+		}
+	}
+}
+
+// Scale3x extends Scale2x to 3x3 blocks, where the edge middles also follow
+// the diagonals that pass through them.
+func Scale3x(dst, src []uint32, width, height int) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			a := at(src, width, height, x-1, y-1)
+			b := at(src, width, height, x, y-1)
+			c := at(src, width, height, x+1, y-1)
+			d := at(src, width, height, x-1, y)
+			e := src[y*width+x]
+			f := at(src, width, height, x+1, y)
+			g := at(src, width, height, x-1, y+1)
+			h := at(src, width, height, x, y+1)
+			i := at(src, width, height, x+1, y+1)
+
+			out := [9]uint32{e, e, e, e, e, e, e, e, e}
+			if b != h && d != f {
+				if d == b {
+					out[0] = d
+				}
+				if (d == b && e != c) || (b == f && e != a) {
+					out[1] = b
+				}
+				if b == f {
+					out[2] = f
+				}
+				if (d == b && e != g) || (d == h && e != a) {
+					out[3] = d
+				}
+				if (b == f && e != i) || (h == f && e != c) {
+					out[5] = f
+				}
+				if d == h {
+					out[6] = d
+				}
+				if (d == h && e != i) || (h == f && e != g) {
+					out[7] = h
+				}
+				if h == f {
+					out[8] = f
+				}
+			}
+
+			for row := 0; row < 3; row++ {
+				copy(dst[(y*3+row)*width*3+x*3:], out[row*3:row*3+3])
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package scale
+
+const (
+	XBR_PAD = 2
+)
+
+// The pairs of pixels xBR compares, written for the bottom right corner with
+// E at (0, 0). The first five weigh an edge across the corner, the next five
+// one running into it, and the last two pick the colour to blend with.
+var XBR_PAIRS = [12][2][2]int{
+	{{0, 0}, {1, -1}}, {{0, 0}, {-1, 1}}, {{1, 1}, {2, 0}}, {{1, 1}, {0, 2}}, {{0, 1}, {1, 0}},
+	{{0, 1}, {-1, 0}}, {{0, 1}, {1, 2}}, {{1, 0}, {2, 1}}, {{1, 0}, {0, -1}}, {{0, 0}, {1, 1}},
+	{{0, 0}, {1, 0}}, {{0, 0}, {0, 1}},
+}
+
+// A distance map holds, for every pixel, its distance to the neighbour to the
+// right, below, below right, and from the right neighbour to the one below.
+const (
+	horizontal = iota
+	vertical
+	diagonal
+	antidiagonal
+)
+
+type xbrTap struct {
+	kind, x, y int
+}
+
+// Xbr is Hyllian's 2xBR at its first level. Each corner weighs how strongly
+// the 5x5 neighbourhood suggests an edge running across it against one
+// running towards it, and where the crossing edge wins the corner is blended
+// halfway to whichever side neighbour is closer in colour.
+//
+// Every comparison is between touching pixels, so the distances are worked
+// out once per frame and shared by the corners that need them.
+func Xbr(dst, src []uint32, width, height int) {
+	stride := width + XBR_PAD*2
+	maps := xbrMaps(src, width, height)
+	taps := xbrTaps()
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			base := (y+XBR_PAD)*stride + x + XBR_PAD
+			e := src[y*width+x]
+			for corner := 0; corner < 4; corner++ {
+				var d [12]int
+				for k, t := range taps[corner] {
+					d[k] = maps[t.kind][base+t.y*stride+t.x]
+				}
+
+				out := e
+				across := d[0] + d[1] + d[2] + d[3] + 4*d[4]
+				along := d[5] + d[6] + d[7] + d[8] + 4*d[9]
+				if across < along {
+					dx, dy := corner%2*2-1, corner/2*2-1
+					next := at(src, width, height, x, y+dy)
+					if d[10] <= d[11] {
+						next = at(src, width, height, x+dx, y)
+					}
+					out = mix(e, next, 0, 1, 1, 0)
+				}
+				dst[(y*2+corner/2)*width*2+x*2+corner%2] = out
+			}
+		}
+	}
+}
+
+// xbrTaps mirrors XBR_PAIRS into each corner, numbered top left, top right,
+// bottom left, bottom right, and finds where each pair sits in the maps.
+func xbrTaps() [4][12]xbrTap {
+	var taps [4][12]xbrTap
+	for corner := range taps {
+		dx, dy := corner%2*2-1, corner/2*2-1
+		for k, pair := range XBR_PAIRS {
+			ax, ay := pair[0][0]*dx, pair[0][1]*dy
+			bx, by := pair[1][0]*dx, pair[1][1]*dy
+			if bx < ax || (bx == ax && by < ay) {
+				ax, ay, bx, by = bx, by, ax, ay
+			}
+
+			switch {
+
+			case ay == by:
+				taps[corner][k] = xbrTap{horizontal, ax, ay}
+
+			case ax == bx:
+				taps[corner][k] = xbrTap{vertical, ax, ay}
+
+			case by > ay:
+				taps[corner][k] = xbrTap{diagonal, ax, ay}
+
+			default:
+				taps[corner][k] = xbrTap{antidiagonal, ax, by}
+			}
+		}
+	}
+	return taps
+}
+
+// xbrMaps builds the distance maps over the frame padded by XBR_PAD on every
+// side, repeating the edge pixels.
+func xbrMaps(src []uint32, width, height int) [4][]int {
+	colors := toYuv(src)
+	stride, rows := width+XBR_PAD*2, height+XBR_PAD*2
+	var maps [4][]int
+	for i := range maps {
+		maps[i] = make([]int, stride*rows)
+	}
+
+	pixel := func(x, y int) yuv {
+		return colors[index(width, height, x-XBR_PAD, y-XBR_PAD)]
+	}
+	for y := 0; y < rows; y++ {
+		for x := 0; x < stride; x++ {
+			i := y*stride + x
+			p := pixel(x, y)
+			maps[horizontal][i] = dist(p, pixel(x+1, y))
+			maps[vertical][i] = dist(p, pixel(x, y+1))
+			maps[diagonal][i] = dist(p, pixel(x+1, y+1))
+			maps[antidiagonal][i] = dist(pixel(x+1, y), pixel(x, y+1))
+		}
+	}
+	return maps
+}
+
+// dist is the weighted YUV distance between two colours.
+func dist(a, b yuv) int {
+	return 48*abs(a.y-b.y) + 7*abs(a.u-b.u) + 6*abs(a.v-b.v)
+}
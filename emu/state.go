@@ -0,0 +1,185 @@
+package emu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	STATE_MAGIC   = "NESS"
+	STATE_VERSION = 6
+)
+
+var (
+	ErrNotState      = errors.New("not a NESify save state")
+	ErrStateVersion  = errors.New("unsupported save state version")
+	ErrStateRom      = errors.New("save state was made for a different ROM")
+	ErrStateTooShort = errors.New("save state is truncated")
+	ErrStateCorrupt  = errors.New("save state is corrupt")
+)
+
+// A save state is the magic, a little-endian version, the SHA-1 of the ROM
+// file it was made from, and then each component's state in a fixed order.
+// Components write their fields with stateWriter and read them back in the
+// same order with stateReader.
+func (nes *NES) SaveState() []uint8 {
+	return nes.appendState(nil)
+}
+
+// appendState writes the state to the end of buf, so callers that snapshot
+// often can reuse one buffer.
+func (nes *NES) appendState(buf []uint8) []uint8 {
+	w := &stateWriter{buf: buf}
+	w.buf = append(w.buf, STATE_MAGIC...)
+	w.u16(STATE_VERSION)
+	w.bytes(nes.cart.hash[:])
+	nes.save(w)
+	return w.buf
+}
+
+// LoadState restores a state made by SaveState. If the state is for another
+// ROM or is malformed, the machine is left as it was.
+func (nes *NES) LoadState(data []uint8) error {
+	if len(data) < len(STATE_MAGIC) || string(data[:len(STATE_MAGIC)]) != STATE_MAGIC {
+		return ErrNotState
+	}
+
+	r := &stateReader{buf: data, pos: len(STATE_MAGIC)}
+	if version := r.u16(); r.err == nil && version != STATE_VERSION {
+		return fmt.Errorf("%w: %d", ErrStateVersion, version)
+	}
+	hash := make([]uint8, len(nes.cart.hash))
+	r.bytes(hash)
+	if r.err != nil {
+		return r.err
+	}
+	if !bytes.Equal(hash, nes.cart.hash[:]) {
+		return ErrStateRom
+	}
+
+	backup := nes.SaveState()
+	nes.load(r)
+	if r.err == nil && r.pos != len(r.buf) {
+		r.err = fmt.Errorf("%w: %d trailing bytes", ErrNotState, len(r.buf)-r.pos)
+	}
+	if r.err != nil {
+		nes.load(&stateReader{buf: backup, pos: len(STATE_MAGIC) + 2 + len(nes.cart.hash)})
+		return r.err
+	}
+	return nil
+}
+
+func (nes *NES) save(w *stateWriter) {
+	nes.cpu.save(w)
+	nes.cpu.bus.save(w)
+	nes.ppu.save(w)
+	nes.ppu.bus.save(w)
+	nes.apu.save(w)
+	nes.controllers.save(w)
+	nes.cart.save(w)
+}
+
+func (nes *NES) load(r *stateReader) {
+	nes.cpu.load(r)
+	nes.cpu.bus.load(r)
+	nes.ppu.load(r)
+	nes.ppu.bus.load(r)
+	nes.apu.load(r)
+	nes.controllers.load(r)
+	nes.cart.load(r)
+}
+
+type stateWriter struct {
+	buf []uint8
+}
+
+func (w *stateWriter) u8(v uint8) {
+	w.buf = append(w.buf, v)
+}
+
+func (w *stateWriter) u16(v uint16) {
+	w.buf = append(w.buf, uint8(v), uint8(v>>8))
+}
+
+func (w *stateWriter) u64(v uint64) {
+	var b [8]uint8
+	binary.LittleEndian.PutUint64(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *stateWriter) i64(v int) {
+	w.u64(uint64(int64(v)))
+}
+
+func (w *stateWriter) bool(v bool) {
+	if v {
+		w.u8(1)
+	} else {
+		w.u8(0)
+	}
+}
+
+func (w *stateWriter) bytes(v []uint8) {
+	w.buf = append(w.buf, v...)
+}
+
+type stateReader struct {
+	buf []uint8
+	pos int
+	err error
+}
+
+func (r *stateReader) next(n int) []uint8 {
+	if r.err != nil {
+		return make([]uint8, n)
+	}
+	if r.pos+n > len(r.buf) {
+		r.err = ErrStateTooShort
+		return make([]uint8, n)
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *stateReader) u8() uint8 {
+	return r.next(1)[0]
+}
+
+func (r *stateReader) u16() uint16 {
+	b := r.next(2)
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func (r *stateReader) u64() uint64 {
+	return binary.LittleEndian.Uint64(r.next(8))
+}
+
+func (r *stateReader) i64() int {
+	return int(int64(r.u64()))
+}
+
+// index reads a bank number, failing the load unless it is below n, so that a
+// corrupt state can't leave a mapper indexing out of range.
+func (r *stateReader) index(n int) int {
+	v := r.i64()
+	r.check(v >= 0 && v < n, fmt.Sprintf("bank %d", v))
+	return v
+}
+
+// check fails the load with what as the reason if ok is false.
+func (r *stateReader) check(ok bool, what string) {
+	if r.err == nil && !ok {
+		r.err = fmt.Errorf("%w: %s out of range", ErrStateCorrupt, what)
+	}
+}
+
+func (r *stateReader) bool() bool {
+	return r.u8() != 0
+}
+
+func (r *stateReader) bytes(v []uint8) {
+	copy(v, r.next(len(v)))
+}
@@ -118,6 +118,10 @@ func (f *Status) resetDecimal() {
 	f.d = 0
 }
 
+func (f *Status) getInterrupt() uint8 {
+	return f.i
+}
+
 func (f *Status) setInterrupt() {
 	f.i = 1
 }
@@ -173,3 +177,15 @@ func (f *Status) checkBorrow(val int, bound int) {
 		f.resetCarry()
 	}
 }
+
+func (f *Status) save(w *stateWriter) {
+	for _, flag := range []uint8{f.n, f.v, f.bit5, f.b, f.d, f.i, f.z, f.c} {
+		w.u8(flag)
+	}
+}
+
+func (f *Status) load(r *stateReader) {
+	for _, flag := range []*uint8{&f.n, &f.v, &f.bit5, &f.b, &f.d, &f.i, &f.z, &f.c} {
+		*flag = r.u8()
+	}
+}
@@ -0,0 +1,194 @@
+// Package testrom runs test ROMs headless until they pass, fail or time out.
+//
+// ROMs following blargg's convention report through PRG-RAM: once $6001-$6003
+// hold the signature DE B0 61, $6000 is $80 while the test runs, $81 when it
+// wants the reset button pressed, and otherwise the result code, 0 meaning
+// pass. $6004 holds a NUL-terminated message. ROMs that only draw their
+// result pass when the screen matches one of a set of known hashes.
+package testrom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/is386/NESify/emu"
+)
+
+const (
+	STATUS_ADDR    = 0x6000
+	SIGNATURE_ADDR = 0x6001
+	TEXT_ADDR      = 0x6004
+	TEXT_END       = 0x8000
+	STATUS_RUNNING = 0x80
+	STATUS_RESET   = 0x81
+	RESET_DELAY    = 6
+	DEFAULT_FRAMES = 60 * 60
+)
+
+var (
+	SIGNATURE = [3]uint8{0xDE, 0xB0, 0x61}
+)
+
+type Outcome int
+
+const (
+	Passed Outcome = iota
+	Failed
+	TimedOut
+)
+
+func (o Outcome) String() string {
+	switch o {
+
+	case Passed:
+		return "pass"
+
+	case Failed:
+		return "fail"
+
+	default:
+		return "timeout"
+	}
+}
+
+// Options sets when to give up and which screens count as a pass. MaxFrames
+// defaults to DEFAULT_FRAMES.
+type Options struct {
+	MaxFrames int
+	Hashes    []string
+}
+
+// Result is how a ROM finished. Code is the blargg result code, if the ROM
+// reported one, and Hash is the hash of the last frame.
+type Result struct {
+	Name     string
+	Outcome  Outcome
+	Code     uint8
+	Message  string
+	Hash     string
+	Frames   int
+	Duration time.Duration
+}
+
+func (r Result) String() string {
+	s := fmt.Sprintf("%s %s after %d frames", strings.ToUpper(r.Outcome.String()), r.Name, r.Frames)
+	if r.Outcome == Failed && r.Code != 0 {
+		s += fmt.Sprintf(", code %d", r.Code)
+	}
+	if r.Message != "" {
+		s += ": " + r.Message
+	}
+	if r.Outcome == TimedOut {
+		s += fmt.Sprintf(" (screen %s)", r.Hash)
+	}
+	return s
+}
+
+func RunFile(path string, opts Options) (Result, error) {
+	rom, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	return Run(filepath.Base(path), rom, opts)
+}
+
+func Run(name string, rom []uint8, opts Options) (Result, error) {
+	nes, err := emu.New(rom, emu.Options{})
+	if err != nil {
+		return Result{}, err
+	}
+	maxFrames := opts.MaxFrames
+	if maxFrames == 0 {
+		maxFrames = DEFAULT_FRAMES
+	}
+
+	start := time.Now()
+	r := Result{Name: name, Outcome: TimedOut}
+	resetAt := 0
+	for r.Frames < maxFrames {
+		nes.StepFrame()
+		r.Frames++
+		r.Hash = ScreenHash(nes)
+
+		if err := nes.Halted(); err != nil {
+			r.Outcome, r.Message = Failed, err.Error()
+			break
+		}
+
+		if hasSignature(nes) {
+			status := nes.PeekCPU(STATUS_ADDR)
+			r.Message = message(nes)
+			switch {
+
+			case status == STATUS_RESET && resetAt == 0:
+				resetAt = r.Frames + RESET_DELAY
+
+			case status == STATUS_RESET && r.Frames >= resetAt:
+				nes.Reset()
+				resetAt = 0
+
+			case status < STATUS_RUNNING:
+				r.Outcome, r.Code = Failed, status
+				if status == 0 {
+					r.Outcome = Passed
+				}
+			}
+			if r.Outcome != TimedOut {
+				break
+			}
+		}
+
+		if contains(opts.Hashes, r.Hash) {
+			r.Outcome = Passed
+			break
+		}
+	}
+	r.Duration = time.Since(start)
+	return r, nil
+}
+
+// ScreenHash identifies the current frame. It hashes palette indices rather
+// than colours so that it doesn't depend on the palette.
+func ScreenHash(nes *emu.NES) string {
+	indices := nes.Indices()
+	data := make([]uint8, len(indices)*2)
+	for i, index := range indices {
+		binary.LittleEndian.PutUint16(data[i*2:], index)
+	}
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+}
+
+func hasSignature(nes *emu.NES) bool {
+	for i, b := range SIGNATURE {
+		if nes.PeekCPU(SIGNATURE_ADDR+uint16(i)) != b {
+			return false
+		}
+	}
+	return true
+}
+
+func message(nes *emu.NES) string {
+	var text []uint8
+	for addr := uint16(TEXT_ADDR); addr < TEXT_END; addr++ {
+		b := nes.PeekCPU(addr)
+		if b == 0 {
+			break
+		}
+		text = append(text, b)
+	}
+	return strings.Join(strings.Fields(string(text)), " ")
+}
+
+func contains(hashes []string, hash string) bool {
+	for _, h := range hashes {
+		if strings.EqualFold(h, hash) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package testrom_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/is386/NESify/emu/testrom"
+	"github.com/is386/NESify/emu/testrom/testromtest"
+)
+
+// Test ROM suites are not part of the repository. Copy a suite's ROMs into
+// testdata/<suite> to run them, along with a file named hashes listing the
+// passing screens of any ROM without a status byte.
+var SUITES = []string{"instr_test-v5", "ppu_vbl_nmi", "sprite_hit_tests", "apu_test"}
+
+func TestSuites(t *testing.T) {
+	for _, suite := range SUITES {
+		suite := suite
+		t.Run(suite, func(t *testing.T) {
+			dir := filepath.Join("testdata", suite)
+			roms, _ := filepath.Glob(filepath.Join(dir, "*.nes"))
+			if len(roms) == 0 {
+				t.Skipf("no ROMs in %s", dir)
+			}
+
+			var opts testrom.Options
+			if data, err := ioutil.ReadFile(filepath.Join(dir, "hashes")); err == nil {
+				opts.Hashes = strings.Fields(string(data))
+			}
+			for _, rom := range roms {
+				rom := rom
+				t.Run(filepath.Base(rom), func(t *testing.T) {
+					testromtest.Check(t, rom, opts)
+				})
+			}
+		})
+	}
+}
+
+// blargg reports status through $6000 with the message "OK" and then loops
+// forever.
+func blargg(status uint8) []uint8 {
+	code := []uint8{
+		0xA9, testrom.STATUS_RUNNING, 0x8D, 0x00, 0x60,
+		0xA9, 0xDE, 0x8D, 0x01, 0x60,
+		0xA9, 0xB0, 0x8D, 0x02, 0x60,
+		0xA9, 0x61, 0x8D, 0x03, 0x60,
+		0xA9, 'O', 0x8D, 0x04, 0x60,
+		0xA9, 'K', 0x8D, 0x05, 0x60,
+		0xA9, status, 0x8D, 0x00, 0x60,
+		0x4C, 0x23, 0xC0,
+	}
+	return nrom(code)
+}
+
+// resetting asks to be reset, then passes once it has been.
+func resetting() []uint8 {
+	return nrom([]uint8{
+		0xA9, 0xDE, 0x8D, 0x01, 0x60,
+		0xA9, 0xB0, 0x8D, 0x02, 0x60,
+		0xA9, 0x61, 0x8D, 0x03, 0x60,
+		0xA5, 0x10, 0xD0, 0x0A, 0xE6, 0x10,
+		0xA9, testrom.STATUS_RESET, 0x8D, 0x00, 0x60,
+		0x4C, 0x1A, 0xC0,
+		0xA9, 0x00, 0x8D, 0x00, 0x60,
+		0x4C, 0x22, 0xC0,
+	})
+}
+
+func nrom(code []uint8) []uint8 {
+	prg := make([]uint8, 0x4000)
+	copy(prg, code)
+	prg[0x3FFC], prg[0x3FFD] = 0x00, 0xC0
+
+	header := []uint8{'N', 'E', 'S', 0x1A, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	return append(append(header, prg...), make([]uint8, 0x2000)...)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		status  uint8
+		outcome testrom.Outcome
+	}{
+		{0, testrom.Passed},
+		{3, testrom.Failed},
+		{testrom.STATUS_RUNNING, testrom.TimedOut},
+	}
+	for _, test := range tests {
+		r, err := testrom.Run("blargg", blargg(test.status), testrom.Options{MaxFrames: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Outcome != test.outcome || r.Message != "OK" {
+			t.Errorf("status $%02X: got %v", test.status, r)
+		}
+		if test.outcome == testrom.Failed && r.Code != test.status {
+			t.Errorf("status $%02X: got code %d", test.status, r.Code)
+		}
+	}
+}
+
+func TestRunReset(t *testing.T) {
+	r, err := testrom.Run("reset", resetting(), testrom.Options{MaxFrames: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Outcome != testrom.Passed || r.Frames <= testrom.RESET_DELAY {
+		t.Errorf("got %v", r)
+	}
+}
+
+func TestRunScreenHash(t *testing.T) {
+	rom := blargg(testrom.STATUS_RUNNING)
+	r, err := testrom.Run("hash", rom, testrom.Options{MaxFrames: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = testrom.Run("hash", rom, testrom.Options{MaxFrames: 10, Hashes: []string{strings.ToUpper(r.Hash)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Outcome != testrom.Passed || r.Frames != 1 {
+		t.Errorf("got %v", r)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []testrom.Result{
+		{Name: "pass.nes", Outcome: testrom.Passed},
+		{Name: "fail.nes", Outcome: testrom.Failed, Code: 2, Message: "Failed"},
+	}
+	var buf bytes.Buffer
+	if err := testrom.WriteJUnit(&buf, "suite", results); err != nil {
+		t.Fatal(err)
+	}
+
+	report := buf.String()
+	for _, want := range []string{`tests="2"`, `failures="1"`, `<failure message="FAIL fail.nes after 0 frames, code 2: Failed" type="fail">`} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %s:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Package testromtest runs test ROMs from Go tests. It is kept apart from
+// testrom so that the emulator binary doesn't link the testing package.
+package testromtest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/is386/NESify/emu/testrom"
+)
+
+// Check runs the ROM at path as part of a Go test, skipping if it is missing.
+func Check(t testing.TB, path string, opts testrom.Options) {
+	t.Helper()
+	r, err := testrom.RunFile(path, opts)
+	if os.IsNotExist(err) {
+		t.Skipf("%s not found", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Outcome != testrom.Passed {
+		t.Error(r)
+	}
+}
@@ -0,0 +1,139 @@
+package emu
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	unofficialNames = map[string]bool{
+		"SLO": true, "RLA": true, "SRE": true, "RRA": true, "SAX": true, "LAX": true,
+		"DCP": true, "ISB": true, "ANC": true, "ALR": true, "ARR": true, "XAA": true,
+		"AXS": true, "AHX": true, "TAS": true, "SHY": true, "SHX": true, "LAS": true,
+		"KIL": true,
+	}
+)
+
+// traceLine describes the instruction about to run and the machine state
+// before it, in the format of nestest.log:
+//
+//	C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+func (c *CPU) traceLine() string {
+	opcode := c.bus.peek(c.pc)
+	var raw []string
+	for i := uint16(0); i <= operandSize(traceMode(opcode)); i++ {
+		raw = append(raw, fmt.Sprintf("%02X", c.bus.peek(c.pc+i)))
+	}
+
+	marker := " "
+	if unofficial(opcode) {
+		marker = "*"
+	}
+	return fmt.Sprintf("%04X  %-8s %s%-32sA:%02X X:%02X Y:%02X P:%02X SP:%02X PPU:%3d,%3d CYC:%d",
+		c.pc, strings.Join(raw, " "), marker, c.disassemble(opcode), c.a, c.x, c.y, c.p.getStatus(), c.s,
+		c.bus.ppu.scanline, c.bus.ppu.cyc, c.totalCyc)
+}
+
+// disassemble works out the operand of the instruction at pc without side
+// effects, showing the address it resolves to and the value there.
+func (c *CPU) disassemble(opcode uint8) string {
+	name := instructionNames[opcode]
+	mode := traceMode(opcode)
+	arg := c.bus.peek(c.pc + 1)
+	arg16 := uint16(c.bus.peek(c.pc+2))<<8 | uint16(arg)
+	peek := func(addr uint16) uint8 {
+		return c.bus.peek(addr)
+	}
+
+	switch mode {
+
+	case Acc:
+		return name + " A"
+
+	case Imm:
+		return fmt.Sprintf("%s #$%02X", name, arg)
+
+	case Zp:
+		return fmt.Sprintf("%s $%02X = %02X", name, arg, peek(uint16(arg)))
+
+	case Zpx, Zpy:
+		index, reg := c.x, "X"
+		if mode == Zpy {
+			index, reg = c.y, "Y"
+		}
+		addr := arg + index
+		return fmt.Sprintf("%s $%02X,%s @ %02X = %02X", name, arg, reg, addr, peek(uint16(addr)))
+
+	case Abs:
+		if opcode == 0x4C || opcode == 0x20 {
+			return fmt.Sprintf("%s $%04X", name, arg16)
+		}
+		return fmt.Sprintf("%s $%04X = %02X", name, arg16, peek(arg16))
+
+	case Abx, Aby:
+		index, reg := c.x, "X"
+		if mode == Aby {
+			index, reg = c.y, "Y"
+		}
+		addr := arg16 + uint16(index)
+		return fmt.Sprintf("%s $%04X,%s @ %04X = %02X", name, arg16, reg, addr, peek(addr))
+
+	case Ind:
+		lo := peek(arg16)
+		hi := peek(arg16&0xFF00 | (arg16+1)&0xFF)
+		return fmt.Sprintf("%s ($%04X) = %04X", name, arg16, uint16(hi)<<8|uint16(lo))
+
+	case Inx:
+		zp := arg + c.x
+		ptr := uint16(peek(uint16(zp+1)))<<8 | uint16(peek(uint16(zp)))
+		return fmt.Sprintf("%s ($%02X,X) @ %02X = %04X = %02X", name, arg, zp, ptr, peek(ptr))
+
+	case Iny:
+		ptr := uint16(peek(uint16(arg+1)))<<8 | uint16(peek(uint16(arg)))
+		addr := ptr + uint16(c.y)
+		return fmt.Sprintf("%s ($%02X),Y = %04X @ %04X = %02X", name, arg, ptr, addr, peek(addr))
+
+	case Rel:
+		return fmt.Sprintf("%s $%04X", name, c.pc+2+uint16(int8(arg)))
+
+	default:
+		return name
+	}
+}
+
+// traceMode is the addressing mode an instruction is shown with. JSR runs as
+// Imm, since it reads the high byte of its target itself, but shows as Abs.
+func traceMode(opcode uint8) AddrMode {
+	if opcode == 0x20 {
+		return Abs
+	}
+	return INSTRUCTIONS[opcode].addrMode
+}
+
+func operandSize(mode AddrMode) uint16 {
+	switch mode {
+
+	case Abs, Abx, Aby, Ind:
+		return 2
+
+	case Imm, Inx, Iny, Rel, Zp, Zpx, Zpy:
+		return 1
+
+	default:
+		return 0
+	}
+}
+
+// unofficial tells whether nestest.log marks opcode with a *.
+func unofficial(opcode uint8) bool {
+	switch opcode {
+
+	case 0xEA:
+		return false
+
+	case 0xEB:
+		return true
+	}
+	name := instructionNames[opcode]
+	return name == "NOP" || unofficialNames[name]
+}
@@ -0,0 +1,105 @@
+package emu
+
+import "github.com/is386/NESify/emu/bits"
+
+var (
+	TRIANGLE_TABLE = [32]uint8{
+		15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+	}
+)
+
+type Triangle struct {
+	enabled, control, linearReload bool
+	length, linear, linearPeriod   uint8
+	timer, timerPeriod             uint16
+	step                           uint8
+}
+
+func NewTriangle() *Triangle {
+	return &Triangle{}
+}
+
+func (t *Triangle) writeRegister(reg uint16, val uint8) {
+	switch reg {
+	case 0:
+		t.control = bits.Test(val, 7)
+		t.linearPeriod = val & 0x7F
+	case 2:
+		t.timerPeriod = (t.timerPeriod & 0x700) | uint16(val)
+	case 3:
+		t.timerPeriod = (t.timerPeriod & 0xFF) | (uint16(val&7) << 8)
+		if t.enabled {
+			t.length = LENGTH_TABLE[val>>3]
+		}
+		t.linearReload = true
+	}
+}
+
+func (t *Triangle) setEnabled(enabled bool) {
+	t.enabled = enabled
+	if !enabled {
+		t.length = 0
+	}
+}
+
+func (t *Triangle) clockTimer() {
+	if t.timer > 0 {
+		t.timer--
+		return
+	}
+	t.timer = t.timerPeriod
+	if t.length > 0 && t.linear > 0 {
+		t.step = (t.step + 1) % 32
+	}
+}
+
+func (t *Triangle) clockLinear() {
+	if t.linearReload {
+		t.linear = t.linearPeriod
+	} else if t.linear > 0 {
+		t.linear--
+	}
+	if !t.control {
+		t.linearReload = false
+	}
+}
+
+func (t *Triangle) clockLength() {
+	if t.length > 0 && !t.control {
+		t.length--
+	}
+}
+
+// Periods below 2 produce ultrasonic output that real hardware smooths into a
+// flat level, so the sequencer is held in the middle instead.
+func (t *Triangle) output() uint8 {
+	if t.timerPeriod < 2 {
+		return 7
+	}
+	return TRIANGLE_TABLE[t.step]
+}
+
+func (t *Triangle) save(w *stateWriter) {
+	w.bool(t.enabled)
+	w.bool(t.control)
+	w.bool(t.linearReload)
+	w.u8(t.length)
+	w.u8(t.linear)
+	w.u8(t.linearPeriod)
+	w.u16(t.timer)
+	w.u16(t.timerPeriod)
+	w.u8(t.step)
+}
+
+func (t *Triangle) load(r *stateReader) {
+	t.enabled = r.bool()
+	t.control = r.bool()
+	t.linearReload = r.bool()
+	t.length = r.u8()
+	t.linear = r.u8()
+	t.linearPeriod = r.u8()
+	t.timer = r.u16()
+	t.timerPeriod = r.u16()
+	t.step = r.u8()
+}
@@ -0,0 +1,74 @@
+package emu
+
+type UxROM struct {
+	prg, chr   []uint8
+	chrRam     bool
+	conflicts  bool
+	bank, last int
+	mirroring  Mirroring
+}
+
+func newUxROM(c *Cart) Mapper {
+	return &UxROM{}
+}
+
+func (u *UxROM) loadRom(h *Header, prg, chr []uint8) {
+	u.prg = prg
+	u.chr, u.chrRam = newChr(h, chr)
+	u.conflicts = hasBusConflicts(h, true)
+	u.last = len(prg)/0x4000 - 1
+	u.mirroring = h.mirroring
+}
+
+func (u *UxROM) prgBankSize() int {
+	return PRG_BANK_SIZE
+}
+
+func (u *UxROM) read(addr uint16) uint8 {
+	switch {
+
+	case addr < 0x2000:
+		return u.chr[int(addr)%len(u.chr)]
+
+	case addr >= 0xC000:
+		return u.prg[u.last*0x4000+int(addr-0xC000)]
+
+	case addr >= 0x8000:
+		return u.prg[u.bank*0x4000+int(addr-0x8000)]
+
+	default:
+		return 0
+	}
+}
+
+func (u *UxROM) write(addr uint16, val uint8) {
+	switch {
+
+	case addr < 0x2000 && u.chrRam:
+		u.chr[int(addr)%len(u.chr)] = val
+
+	case addr >= 0x8000:
+		if u.conflicts {
+			val &= u.read(addr)
+		}
+		u.bank = int(val) % (u.last + 1)
+	}
+}
+
+func (u *UxROM) getMirroring() Mirroring {
+	return u.mirroring
+}
+
+func (u *UxROM) save(w *stateWriter) {
+	if u.chrRam {
+		w.bytes(u.chr)
+	}
+	w.i64(u.bank)
+}
+
+func (u *UxROM) load(r *stateReader) {
+	if u.chrRam {
+		r.bytes(u.chr)
+	}
+	u.bank = r.index(u.last + 1)
+}
@@ -0,0 +1,30 @@
+package emu
+
+// A VideoSink receives each finished frame as NES_WIDTH*NES_HEIGHT pixels in
+// 0xRRGGBB form. The slice is reused by the PPU, so sinks that keep a frame
+// must copy it.
+type VideoSink interface {
+	DrawFrame(pixels []uint32)
+}
+
+type MemorySink struct {
+	pixels [NES_WIDTH * NES_HEIGHT]uint32
+	frames int
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (m *MemorySink) DrawFrame(pixels []uint32) {
+	copy(m.pixels[:], pixels)
+	m.frames++
+}
+
+func (m *MemorySink) Pixels() []uint32 {
+	return m.pixels[:]
+}
+
+func (m *MemorySink) Frames() int {
+	return m.frames
+}
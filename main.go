@@ -2,14 +2,44 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 
 	"github.com/akamensky/argparse"
 	"github.com/is386/NESify/emu"
+	"github.com/is386/NESify/emu/scale"
+	"github.com/is386/NESify/ui"
 	"github.com/sqweek/dialog"
 )
 
-func parseArgs() bool {
+var (
+	pacingModes = map[string]ui.PacingMode{
+		"wall":  ui.WallClock,
+		"audio": ui.AudioSync,
+		"none":  ui.Unthrottled,
+	}
+
+	scalingModes = map[string]ui.Scaling{
+		"integer": ui.IntegerScaling,
+		"aspect":  ui.AspectScaling,
+	}
+
+	ntscPresets = map[string]emu.NtscFilterSettings{
+		"composite": emu.NTSC_COMPOSITE,
+		"svideo":    emu.NTSC_SVIDEO,
+		"rgb":       emu.NTSC_RGB,
+	}
+)
+
+type args struct {
+	rom       string
+	headless  bool
+	palette   string
+	ntsc      emu.NtscSettings
+	uiOptions ui.Options
+}
+
+func parseArgs() args {
 	parser := argparse.NewParser("NESify", "A simple NES emulator written in Go.")
 
 	debugFlag := parser.Flag("d", "debug",
@@ -19,22 +49,247 @@ func parseArgs() bool {
 			Default:  false,
 		})
 
+	pacingFlag := parser.Selector("p", "pacing", []string{"wall", "audio", "none"},
+		&argparse.Options{
+			Required: false,
+			Help:     "Frame pacing: wall clock, audio sync or unthrottled",
+			Default:  "audio",
+		})
+
+	romFlag := parser.String("r", "rom",
+		&argparse.Options{
+			Required: false,
+			Help:     "ROM to load instead of asking with a file dialog",
+		})
+
+	headlessFlag := parser.Flag("", "headless",
+		&argparse.Options{
+			Required: false,
+			Help:     "Runs unthrottled without a window or audio device",
+			Default:  false,
+		})
+
+	framesFlag := parser.Int("f", "frames",
+		&argparse.Options{
+			Required: false,
+			Help:     "Stops after this many frames, or never if 0",
+			Default:  0,
+		})
+
+	rewindFlag := parser.Int("", "rewind",
+		&argparse.Options{
+			Required: false,
+			Help:     "Megabytes of memory to keep for rewinding, or 0 to turn it off",
+			Default:  32,
+		})
+
+	paletteFlag := parser.String("", "palette",
+		&argparse.Options{
+			Required: false,
+			Help:     "A .pal file to use for colours, or ntsc to generate one",
+		})
+
+	hueFlag := parser.Float("", "hue",
+		&argparse.Options{
+			Required: false,
+			Help:     "Hue shift in degrees for the ntsc palette",
+			Default:  emu.DEFAULT_NTSC.Hue,
+		})
+
+	saturationFlag := parser.Float("", "saturation",
+		&argparse.Options{
+			Required: false,
+			Help:     "Saturation for the ntsc palette",
+			Default:  emu.DEFAULT_NTSC.Saturation,
+		})
+
+	contrastFlag := parser.Float("", "contrast",
+		&argparse.Options{
+			Required: false,
+			Help:     "Contrast for the ntsc palette",
+			Default:  emu.DEFAULT_NTSC.Contrast,
+		})
+
+	gammaFlag := parser.Float("", "gamma",
+		&argparse.Options{
+			Required: false,
+			Help:     "Display gamma for the ntsc palette",
+			Default:  emu.DEFAULT_NTSC.Gamma,
+		})
+
+	ntscFlag := parser.Selector("", "ntsc", []string{"none", "composite", "svideo", "rgb"},
+		&argparse.Options{
+			Required: false,
+			Help:     "NTSC filter to emulate a TV connection with",
+			Default:  "none",
+		})
+
+	scalingFlag := parser.Selector("", "scaling", []string{"integer", "aspect"},
+		&argparse.Options{
+			Required: false,
+			Help:     "Scale by whole multiples with square pixels, or fill the window at the 8:7 TV pixel aspect",
+			Default:  "integer",
+		})
+
+	vsyncFlag := parser.Flag("", "vsync",
+		&argparse.Options{
+			Required: false,
+			Help:     "Waits for vertical sync when showing each frame",
+			Default:  false,
+		})
+
+	fullscreenFlag := parser.Flag("", "fullscreen",
+		&argparse.Options{
+			Required: false,
+			Help:     "Starts in fullscreen",
+			Default:  false,
+		})
+
+	var scalers, overlays []string
+	for _, s := range scale.SCALERS {
+		scalers = append(scalers, s.Name)
+	}
+	for _, o := range scale.OVERLAYS {
+		overlays = append(overlays, o.Name)
+	}
+
+	scalerFlag := parser.Selector("", "scaler", scalers,
+		&argparse.Options{
+			Required: false,
+			Help:     "Pixel art scaler to start with",
+			Default:  "none",
+		})
+
+	overlayFlag := parser.Selector("", "overlay", overlays,
+		&argparse.Options{
+			Required: false,
+			Help:     "Scanline or CRT mask overlay to start with",
+			Default:  "none",
+		})
+
 	err := parser.Parse(os.Args)
 	if err != nil {
 		fmt.Print(parser.Usage(err))
 		os.Exit(0)
 	}
 
-	return *debugFlag
+	ntsc := emu.NtscSettings{
+		Hue:        *hueFlag,
+		Saturation: *saturationFlag,
+		Contrast:   *contrastFlag,
+		Gamma:      *gammaFlag,
+	}
+
+	var filter *emu.NtscFilterSettings
+	if preset, ok := ntscPresets[*ntscFlag]; ok {
+		preset.NtscSettings = ntsc
+		filter = &preset
+	}
+
+	return args{
+		rom:      *romFlag,
+		headless: *headlessFlag,
+		palette:  *paletteFlag,
+		ntsc:     ntsc,
+		uiOptions: ui.Options{
+			Debug:        *debugFlag,
+			Pacing:       pacingModes[*pacingFlag],
+			MaxFrames:    *framesFlag,
+			RewindBudget: *rewindFlag << 20,
+			Ntsc:         filter,
+			Scaling:      scalingModes[*scalingFlag],
+			VSync:        *vsyncFlag,
+			Fullscreen:   *fullscreenFlag,
+			Scaler:       indexOf(scalers, *scalerFlag),
+			Overlay:      indexOf(overlays, *overlayFlag),
+		},
+	}
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return 0
+}
+
+func loadPalette(a args) (*emu.Palette, error) {
+	if a.palette == "ntsc" {
+		return emu.NewNtscPalette(a.ntsc), nil
+	}
+	data, err := ioutil.ReadFile(a.palette)
+	if err != nil {
+		return nil, err
+	}
+	return emu.LoadPalette(data)
+}
+
+func runHeadless(nes *emu.NES, opts ui.Options) {
+	for frames := 0; opts.MaxFrames == 0 || frames < opts.MaxFrames; frames++ {
+		nes.StepFrame()
+		nes.AudioSamples()
+		if err := nes.Halted(); err != nil {
+			fmt.Println(err)
+			break
+		}
+	}
+	if opts.Battery != nil {
+		if err := opts.Battery.Flush(); err != nil {
+			fmt.Println(err)
+		}
+	}
 }
 
 func main() {
-	debug := parseArgs()
-	romFileName, err := dialog.File().Filter("NES Rom File", "nes").Load()
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		runTests(os.Args[1:])
+		return
+	}
+
+	a := parseArgs()
+	if a.rom == "" {
+		var err error
+		a.rom, err = dialog.File().Filter("NES Rom File", "nes").Load()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	rom, err := ioutil.ReadFile(a.rom)
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(0)
 	}
-	n := emu.NewNES(romFileName, debug)
-	n.Run()
 
+	n, err := emu.New(rom, emu.Options{Debug: a.uiOptions.Debug})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(0)
+	}
+
+	if a.palette != "" {
+		palette, err := loadPalette(a)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(0)
+		}
+		n.SetPalette(palette)
+	}
+
+	if ram := n.BatteryRAM(); ram != nil {
+		a.uiOptions.Battery, err = emu.NewBattery(emu.SavePath(a.rom), ram)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(0)
+		}
+	}
+
+	a.uiOptions.RomPath = a.rom
+	if a.headless {
+		runHeadless(n, a.uiOptions)
+	} else {
+		ui.Run(n, a.uiOptions)
+	}
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/akamensky/argparse"
+	"github.com/is386/NESify/emu/testrom"
+)
+
+// runTests is the test subcommand. Arguments ending in .nes are the ROMs to
+// run and the rest are flags. It exits with status 1 if any ROM doesn't pass.
+func runTests(args []string) {
+	parser := argparse.NewParser("NESify test", "Runs test ROMs headless and reports whether they pass.")
+
+	framesFlag := parser.Int("f", "frames",
+		&argparse.Options{
+			Required: false,
+			Help:     "Frames to run each ROM for before it times out",
+			Default:  testrom.DEFAULT_FRAMES,
+		})
+
+	hashFlag := parser.StringList("", "hash",
+		&argparse.Options{
+			Required: false,
+			Help:     "Screen hash that counts as a pass, for ROMs without a status byte at $6000",
+		})
+
+	junitFlag := parser.String("j", "junit",
+		&argparse.Options{
+			Required: false,
+			Help:     "Writes a JUnit XML report to this file",
+		})
+
+	suiteFlag := parser.String("s", "suite",
+		&argparse.Options{
+			Required: false,
+			Help:     "Test suite name for the JUnit report",
+			Default:  "nesify",
+		})
+
+	var roms, flags []string
+	for _, arg := range args {
+		if strings.HasSuffix(strings.ToLower(arg), ".nes") {
+			roms = append(roms, arg)
+		} else {
+			flags = append(flags, arg)
+		}
+	}
+
+	err := parser.Parse(flags)
+	if err == nil && len(roms) == 0 {
+		err = fmt.Errorf("no ROMs given")
+	}
+	if err != nil {
+		fmt.Print(parser.Usage(err))
+		os.Exit(1)
+	}
+
+	opts := testrom.Options{MaxFrames: *framesFlag, Hashes: *hashFlag}
+	var results []testrom.Result
+	failed := false
+	for _, path := range roms {
+		r, err := testrom.RunFile(path, opts)
+		if err != nil {
+			r = testrom.Result{Name: path, Outcome: testrom.Failed, Message: err.Error()}
+		}
+		fmt.Println(r)
+		results = append(results, r)
+		failed = failed || r.Outcome != testrom.Passed
+	}
+
+	if *junitFlag != "" {
+		if err := writeJUnit(*junitFlag, *suiteFlag, results); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func writeJUnit(path, suite string, results []testrom.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := testrom.WriteJUnit(f, suite, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/is386/NESify/emu"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	// Samples are dropped once a quarter second of 32-bit audio is queued, so
+	// latency can't build up when emulation runs ahead of the audio device.
+	MAX_QUEUED_AUDIO = emu.SAMPLE_RATE / 4 * 4
+)
+
+type Audio struct {
+	dev sdl.AudioDeviceID
+	buf []byte
+}
+
+func NewAudio(sampleRate int) *Audio {
+	if err := sdl.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		panic(err)
+	}
+
+	spec := &sdl.AudioSpec{
+		Freq:     int32(sampleRate),
+		Format:   sdl.AUDIO_F32,
+		Channels: 1,
+		Samples:  1024,
+	}
+	dev, err := sdl.OpenAudioDevice("", false, spec, nil, 0)
+	if err != nil {
+		panic(err)
+	}
+	sdl.PauseAudioDevice(dev, false)
+	return &Audio{dev: dev}
+}
+
+func (a *Audio) queue(samples []float32) {
+	if len(samples) == 0 || sdl.GetQueuedAudioSize(a.dev) > MAX_QUEUED_AUDIO {
+		return
+	}
+	if cap(a.buf) < len(samples)*4 {
+		a.buf = make([]byte, len(samples)*4)
+	}
+	a.buf = a.buf[:len(samples)*4]
+	for i, s := range samples {
+		binary.LittleEndian.PutUint32(a.buf[i*4:], math.Float32bits(s))
+	}
+	sdl.QueueAudio(a.dev, a.buf)
+}
+
+func (a *Audio) queued() int {
+	return int(sdl.GetQueuedAudioSize(a.dev)) / 4
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/is386/NESify/emu"
+)
+
+const (
+	// The audio pacer keeps this many samples queued, and nudges the APU
+	// sample rate by up to MAX_RATE_DELTA to hold the queue there.
+	AUDIO_TARGET   = emu.SAMPLE_RATE / 20
+	MAX_RATE_DELTA = 0.005
+)
+
+type PacingMode int
+
+const (
+	WallClock PacingMode = iota
+	AudioSync
+	Unthrottled
+)
+
+func newPacer(mode PacingMode, nes *emu.NES, audio *Audio) emu.Pacer {
+	switch mode {
+	case AudioSync:
+		return &AudioPacer{clock: emu.NewFrameClock(), nes: nes, audio: audio}
+	case Unthrottled:
+		return emu.NewUnthrottledPacer()
+	default:
+		return emu.NewWallClockPacer()
+	}
+}
+
+type audioQueue interface {
+	queued() int
+}
+
+type sampleRater interface {
+	SetSampleRate(rate int)
+}
+
+type AudioPacer struct {
+	clock emu.FrameClock
+	nes   sampleRater
+	audio audioQueue
+}
+
+// The APU sample rate is adjusted in proportion to how far the queue is from
+// the target, which keeps it from slowly draining or overflowing without
+// audible pitch changes. The audio device consumes samples at its own rate, so
+// blocking while the queue is over target then keeps emulation locked to it.
+func (p *AudioPacer) Pace() {
+	p.clock.Tick()
+	fill := float64(p.audio.queued()) / float64(AUDIO_TARGET)
+	delta := MAX_RATE_DELTA * (1 - fill)
+	switch {
+
+	case delta > MAX_RATE_DELTA:
+		delta = MAX_RATE_DELTA
+
+	case delta < -MAX_RATE_DELTA:
+		delta = -MAX_RATE_DELTA
+	}
+	p.nes.SetSampleRate(int(emu.SAMPLE_RATE * (1 + delta)))
+
+	for p.audio.queued() > AUDIO_TARGET {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (p *AudioPacer) Drift() time.Duration {
+	return p.clock.Drift()
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/is386/NESify/emu"
+)
+
+// fakeQueue reports each of its levels in turn, then stays at the last, as if
+// the audio device drained it.
+type fakeQueue struct {
+	levels []int
+}
+
+func (q *fakeQueue) queued() int {
+	level := q.levels[0]
+	if len(q.levels) > 1 {
+		q.levels = q.levels[1:]
+	}
+	return level
+}
+
+type fakeNes struct {
+	rate int
+}
+
+func (n *fakeNes) SetSampleRate(rate int) {
+	n.rate = rate
+}
+
+// An under-full queue speeds the sample rate up and an over-full one slows it
+// down, by at most MAX_RATE_DELTA.
+func TestAudioPacer(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []int
+		delta  float64
+	}{
+		{"empty", []int{0}, MAX_RATE_DELTA},
+		{"half full", []int{AUDIO_TARGET / 2}, MAX_RATE_DELTA / 2},
+		{"on target", []int{AUDIO_TARGET}, 0},
+		{"over-full", []int{AUDIO_TARGET * 3 / 2, AUDIO_TARGET, 0}, -MAX_RATE_DELTA / 2},
+		{"full", []int{AUDIO_TARGET * 3, AUDIO_TARGET, 0}, -MAX_RATE_DELTA},
+	}
+	for _, test := range tests {
+		nes := &fakeNes{}
+		p := &AudioPacer{clock: emu.NewFrameClock(), nes: nes, audio: &fakeQueue{test.levels}}
+		p.Pace()
+		if want := int(emu.SAMPLE_RATE * (1 + test.delta)); nes.rate != want {
+			t.Errorf("%s: sample rate %d, want %d", test.name, nes.rate, want)
+		}
+	}
+}
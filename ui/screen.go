@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/is386/NESify/emu"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	SCALE        = 2
+	PIXEL_ASPECT = 8.0 / 7.0
+)
+
+var (
+	buttonMap = map[sdl.Keycode]emu.Button{
+		sdl.K_RETURN: emu.Start,
+		sdl.K_RSHIFT: emu.Select,
+		sdl.K_w:      emu.Up,
+		sdl.K_s:      emu.Down,
+		sdl.K_a:      emu.Left,
+		sdl.K_d:      emu.Right,
+		sdl.K_j:      emu.A,
+		sdl.K_k:      emu.B,
+	}
+)
+
+type Scaling int
+
+const (
+	IntegerScaling Scaling = iota
+	AspectScaling
+)
+
+// Screen streams frames into a texture that the renderer scales to fit the
+// window. Whatever size the frames are, they are shown as NES_WIDTH*NES_HEIGHT
+// NES pixels, either square at a whole multiple or stretched to the 8:7 pixel
+// aspect ratio of a TV.
+type Screen struct {
+	width, height     int
+	scaling           Scaling
+	vsync, fullscreen bool
+	win               *sdl.Window
+	renderer          *sdl.Renderer
+	texture           *sdl.Texture
+}
+
+func NewScreen(scaling Scaling, vsync bool) *Screen {
+	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
+		panic(err)
+	}
+
+	winWidth := float64(emu.NES_WIDTH * SCALE)
+	if scaling == AspectScaling {
+		winWidth *= PIXEL_ASPECT
+	}
+	win, err := sdl.CreateWindow("", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		int32(math.Round(winWidth)), int32(emu.NES_HEIGHT*SCALE), sdl.WINDOW_ALLOW_HIGHDPI|sdl.WINDOW_RESIZABLE)
+	if err != nil {
+		panic(err)
+	}
+
+	var flags uint32 = sdl.RENDERER_ACCELERATED
+	if vsync {
+		flags |= sdl.RENDERER_PRESENTVSYNC
+	}
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "nearest")
+	renderer, err := sdl.CreateRenderer(win, -1, flags)
+	if err != nil {
+		panic(err)
+	}
+
+	s := Screen{scaling: scaling, vsync: vsync, win: win, renderer: renderer}
+	return &s
+}
+
+// draw shows a width*height frame, making a new texture when the size changes.
+func (s *Screen) draw(pixels []uint32, width, height int) {
+	if s.texture == nil || width != s.width || height != s.height {
+		if s.texture != nil {
+			s.texture.Destroy()
+		}
+		texture, err := s.renderer.CreateTexture(sdl.PIXELFORMAT_RGB888, sdl.TEXTUREACCESS_STREAMING,
+			int32(width), int32(height))
+		if err != nil {
+			panic(err)
+		}
+		s.texture, s.width, s.height = texture, width, height
+	}
+
+	if err := s.texture.UpdateRGBA(nil, pixels, s.width); err != nil {
+		fmt.Println(err)
+		return
+	}
+	s.renderer.SetDrawColor(0, 0, 0, 0xFF)
+	s.renderer.Clear()
+	s.renderer.Copy(s.texture, nil, s.viewport())
+	s.renderer.Present()
+}
+
+// viewport centres the picture in the window at the largest size the scaling
+// mode allows.
+func (s *Screen) viewport() *sdl.Rect {
+	outWidth, outHeight, err := s.renderer.GetOutputSize()
+	if err != nil {
+		return nil
+	}
+
+	var w, h int32
+	switch s.scaling {
+
+	case IntegerScaling:
+		scale := outWidth / emu.NES_WIDTH
+		if outHeight/emu.NES_HEIGHT < scale {
+			scale = outHeight / emu.NES_HEIGHT
+		}
+		if scale < 1 {
+			scale = 1
+		}
+		w, h = emu.NES_WIDTH*scale, emu.NES_HEIGHT*scale
+
+	case AspectScaling:
+		width := emu.NES_WIDTH * PIXEL_ASPECT
+		scale := math.Min(float64(outWidth)/width, float64(outHeight)/emu.NES_HEIGHT)
+		w, h = int32(math.Round(width*scale)), int32(math.Round(emu.NES_HEIGHT*scale))
+	}
+	return &sdl.Rect{X: (outWidth - w) / 2, Y: (outHeight - h) / 2, W: w, H: h}
+}
+
+func (s *Screen) toggleFullscreen() {
+	s.fullscreen = !s.fullscreen
+	var flags uint32
+	if s.fullscreen {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	if err := s.win.SetFullscreen(flags); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (s *Screen) toggleVsync() {
+	s.vsync = !s.vsync
+	if err := s.renderer.RenderSetVSync(s.vsync); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (s *Screen) toggleScaling() {
+	if s.scaling == IntegerScaling {
+		s.scaling = AspectScaling
+	} else {
+		s.scaling = IntegerScaling
+	}
+}
+
+// pollEvents passes controller keys to nes and any other key presses and
+// releases to hotkey. It returns false once the window is closed.
+func (s *Screen) pollEvents(nes *emu.NES, hotkey func(key sdl.Keycode, down bool)) bool {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch e := event.(type) {
+		case *sdl.QuitEvent:
+			return false
+		case *sdl.KeyboardEvent:
+			if b, ok := buttonMap[e.Keysym.Sym]; ok {
+				nes.SetButton(0, b, e.Type == sdl.KEYDOWN)
+			} else if e.Repeat == 0 {
+				hotkey(e.Keysym.Sym, e.Type == sdl.KEYDOWN)
+			}
+		}
+	}
+	return true
+}
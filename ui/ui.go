@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/is386/NESify/emu"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	FPS            = 60
+	SAVE_INTERVAL  = 5 * FPS
+	REWIND_SECONDS = 60
+)
+
+type Options struct {
+	Debug        bool
+	Pacing       PacingMode
+	MaxFrames    int
+	Battery      *emu.Battery
+	RomPath      string
+	RewindBudget int
+	Ntsc         *emu.NtscFilterSettings
+	Scaling      Scaling
+	VSync        bool
+	Fullscreen   bool
+	Scaler       int
+	Overlay      int
+}
+
+type session struct {
+	nes       *emu.NES
+	screen    *Screen
+	video     *video
+	opts      Options
+	slot      int
+	rewind    *emu.Rewind
+	rewinding bool
+	halted    bool
+}
+
+// Run opens a window and an audio device and plays nes until the window is
+// closed or MaxFrames frames have been shown.
+//
+// Keys 1-9 pick a save state slot, F5 saves to it and F7 loads from it.
+// Holding Backspace rewinds, if RewindBudget bytes are set aside for it.
+// F9 toggles vsync, F10 switches between integer and aspect-correct scaling
+// and F11 toggles fullscreen. F2 and F3 cycle through the scalers and
+// overlays, which Scaler and Overlay pick from scale.SCALERS and
+// scale.OVERLAYS to begin with.
+func Run(nes *emu.NES, opts Options) {
+	screen := NewScreen(opts.Scaling, opts.VSync)
+	screen.win.SetTitle("NESify")
+	if opts.Fullscreen {
+		screen.toggleFullscreen()
+	}
+	video := newVideo(nes, screen, opts)
+	nes.SetVideoSink(video)
+
+	audio := NewAudio(emu.SAMPLE_RATE)
+	pacer := newPacer(opts.Pacing, nes, audio)
+	s := &session{nes: nes, screen: screen, video: video, opts: opts, slot: 1}
+	if opts.RewindBudget > 0 {
+		s.rewind = emu.NewRewind(nes, opts.RewindBudget, REWIND_SECONDS*FPS)
+	}
+
+	running := true
+	for frames := 1; running; frames++ {
+		s.step()
+		s.checkHalt()
+		audio.queue(nes.AudioSamples())
+		running = screen.pollEvents(nes, s.hotkey)
+		pacer.Pace()
+
+		if opts.Debug && frames%FPS == 0 {
+			fmt.Fprintf(os.Stderr, "frame %d drift %v\n", frames, pacer.Drift())
+		}
+		if frames%SAVE_INTERVAL == 0 {
+			saveBattery(opts.Battery)
+		}
+		if opts.MaxFrames > 0 && frames >= opts.MaxFrames {
+			running = false
+		}
+	}
+	saveBattery(opts.Battery)
+}
+
+// While rewinding, each frame goes back to the previous recorded state and
+// runs it forward once so there is a picture to show.
+func (s *session) step() {
+	if s.rewind == nil {
+		s.nes.StepFrame()
+		return
+	}
+	if s.rewinding {
+		if s.rewind.Pop() {
+			s.nes.StepFrame()
+		}
+		return
+	}
+	s.nes.StepFrame()
+	s.rewind.Push()
+}
+
+// checkHalt reports the CPU locking up in the title bar. The window stays open
+// so that a save state can be loaded or the halt rewound.
+func (s *session) checkHalt() {
+	err := s.nes.Halted()
+	if (err != nil) == s.halted {
+		return
+	}
+	s.halted = err != nil
+
+	title := "NESify"
+	if err != nil {
+		fmt.Println(err)
+		title += " - " + err.Error()
+	}
+	s.screen.win.SetTitle(title)
+}
+
+func (s *session) hotkey(key sdl.Keycode, down bool) {
+	if key == sdl.K_BACKSPACE {
+		s.rewinding = down
+	}
+	if !down {
+		return
+	}
+
+	switch {
+	case key >= sdl.K_1 && key <= sdl.K_9:
+		s.slot = int(key - sdl.K_0)
+	case key == sdl.K_F2:
+		s.video.nextScaler()
+	case key == sdl.K_F3:
+		s.video.nextOverlay()
+	case key == sdl.K_F5:
+		s.saveState()
+	case key == sdl.K_F7:
+		s.loadState()
+	case key == sdl.K_F9:
+		s.screen.toggleVsync()
+	case key == sdl.K_F10:
+		s.screen.toggleScaling()
+	case key == sdl.K_F11:
+		s.screen.toggleFullscreen()
+	}
+}
+
+func (s *session) statePath() string {
+	base := strings.TrimSuffix(s.opts.RomPath, filepath.Ext(s.opts.RomPath))
+	return fmt.Sprintf("%s.ss%d", base, s.slot)
+}
+
+func (s *session) saveState() {
+	if err := ioutil.WriteFile(s.statePath(), s.nes.SaveState(), 0644); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (s *session) loadState() {
+	data, err := ioutil.ReadFile(s.statePath())
+	if err == nil {
+		err = s.nes.LoadState(data)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if s.rewind != nil {
+		s.rewind.Clear()
+	}
+}
+
+func saveBattery(b *emu.Battery) {
+	if b == nil {
+		return
+	}
+	if err := b.Flush(); err != nil {
+		fmt.Println(err)
+	}
+}
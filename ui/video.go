@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"github.com/is386/NESify/emu"
+	"github.com/is386/NESify/emu/scale"
+)
+
+// video takes each frame through the optional NTSC filter, then the scaler
+// and overlay, before drawing it.
+type video struct {
+	nes             *emu.NES
+	screen          *Screen
+	ntsc            *emu.NtscFilter
+	scaler, overlay int
+	filtered        []uint32
+	scaled          []uint32
+	overlaid        []uint32
+}
+
+func newVideo(nes *emu.NES, screen *Screen, opts Options) *video {
+	v := &video{nes: nes, screen: screen, scaler: opts.Scaler, overlay: opts.Overlay}
+	if opts.Ntsc != nil {
+		v.ntsc = emu.NewNtscFilter(*opts.Ntsc)
+		v.filtered = make([]uint32, emu.NTSC_WIDTH*emu.NES_HEIGHT)
+	}
+	return v
+}
+
+func (v *video) DrawFrame(pixels []uint32) {
+	width, height := emu.NES_WIDTH, emu.NES_HEIGHT
+	if v.ntsc != nil {
+		v.ntsc.Filter(v.nes.Indices(), v.nes.Frame(), v.filtered)
+		pixels, width = v.filtered, emu.NTSC_WIDTH
+	}
+
+	scaler := scale.SCALERS[v.scaler]
+	if scaler.Factor > 1 {
+		v.scaled = resize(v.scaled, width*height*scaler.Factor*scaler.Factor)
+		scaler.Apply(v.scaled, pixels, width, height)
+		pixels, width, height = v.scaled, width*scaler.Factor, height*scaler.Factor
+	}
+
+	if v.overlay != 0 {
+		v.overlaid = resize(v.overlaid, width*height)
+		scale.OVERLAYS[v.overlay].Apply(v.overlaid, pixels, width, height, height/emu.NES_HEIGHT)
+		pixels = v.overlaid
+	}
+	v.screen.draw(pixels, width, height)
+}
+
+func (v *video) nextScaler() {
+	v.scaler = (v.scaler + 1) % len(scale.SCALERS)
+}
+
+func (v *video) nextOverlay() {
+	v.overlay = (v.overlay + 1) % len(scale.OVERLAYS)
+}
+
+func resize(buf []uint32, size int) []uint32 {
+	if cap(buf) < size {
+		return make([]uint32, size)
+	}
+	return buf[:size]
+}